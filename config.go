@@ -0,0 +1,177 @@
+package main
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/HumXC/give-me-time/engine/project"
+	"golang.org/x/term"
+)
+
+// config [-profile name] <project>：逐个询问工程中的选项，并保存到工程的 profiles 目录中
+func runConfig(args []string) error {
+	fs := flag.NewFlagSet("config", flag.ContinueOnError)
+	profile := fs.String("profile", project.DefaultProfile, "要编辑的 profile 的名称")
+	err := fs.Parse(args)
+	if err != nil {
+		return err
+	}
+	if fs.NArg() == 0 {
+		return fmt.Errorf("usage: config [-profile name] <project>")
+	}
+	name := fs.Arg(0)
+	p, err := openProject(name)
+	if err != nil {
+		return err
+	}
+	if len(p.Option) == 0 {
+		fmt.Println("project has no option")
+		return nil
+	}
+	dir := name
+	if filepath.Ext(name) == project.ArchiveExt {
+		dir = filepath.Dir(name)
+	}
+	// 已经存在的 profile 作为当前值，不存在时使用默认值
+	current, err := loadProfile(name, *profile, p.Option)
+	if err != nil {
+		fmt.Println(err)
+		current, err = project.ParseOption(p.Option, "")
+		if err != nil {
+			return err
+		}
+	}
+	values, err := configure(os.Stdin, os.Stdout, p.Option, current)
+	if err != nil {
+		return err
+	}
+	file, err := project.ProfilePath(dir, *profile)
+	if err != nil || file == "" {
+		file = filepath.Join(dir, project.ProfileDir, *profile+".yaml")
+	}
+	err = project.SaveProfile(file, values)
+	if err != nil {
+		return err
+	}
+	fmt.Println("saved to", file)
+	return nil
+}
+
+// 在 out 中显示每个选项的说明，并从 in 中读取用户的输入，输入为空时使用 current 中的值
+// 输入不合法时会重新询问，所有的输入都通过同一个 bufio.Reader 读取，参考 readSecret
+func configure(in io.Reader, out io.Writer, opts []project.Option, current map[string]any) (map[string]any, error) {
+	r := bufio.NewReader(in)
+	values := make(map[string]any, len(opts))
+	masked := project.MaskOption(opts, current)
+	for _, opt := range opts {
+		fmt.Fprintf(out, "\n%s (%s)\n", opt.Name, optionType(opt))
+		if opt.Discription != "" {
+			fmt.Fprintf(out, "  %s\n", opt.Discription)
+		}
+		for _, e := range opt.Enum {
+			if e.Label != "" {
+				fmt.Fprintf(out, "  - %s: %s\n", e.Value, e.Label)
+			} else {
+				fmt.Fprintf(out, "  - %s\n", e.Value)
+			}
+		}
+		if len(opt.Select) != 0 {
+			only := ""
+			if opt.IsOnlySelect {
+				only = ", only"
+			}
+			fmt.Fprintf(out, "  select%s: %v\n", only, opt.Select)
+		}
+		if opt.Min != nil || opt.Max != nil {
+			fmt.Fprintf(out, "  range: %s - %s\n", bound(opt.Min), bound(opt.Max))
+		}
+		for {
+			fmt.Fprintf(out, "[%v]> ", format(masked[opt.Name]))
+			text, isSecret, err := readSecret(in, r, opt)
+			if err != nil {
+				return nil, err
+			}
+			if isSecret {
+				fmt.Fprintln(out)
+			} else {
+				text, err = readLine(r)
+				if err != nil {
+					return nil, err
+				}
+			}
+			if strings.TrimSpace(text) == "" {
+				values[opt.Name] = current[opt.Name]
+				break
+			}
+			v, err := project.ParseOptionValue(opt, text)
+			if err != nil {
+				fmt.Fprintln(out, "  invalid:", err)
+				continue
+			}
+			values[opt.Name] = v
+			break
+		}
+	}
+	return values, nil
+}
+
+// secret 类型的选项在 in 是终端时不回显地读取，ok 为 false 时需要从 r 中正常读取
+// r 中已经缓存了输入时（例如一次粘贴了多行）也从 r 中读取，避免缓存的输入被后面的选项使用
+func readSecret(in io.Reader, r *bufio.Reader, opt project.Option) (text string, ok bool, err error) {
+	f, isFile := in.(*os.File)
+	if opt.Type != project.OptionSecret || !isFile || r.Buffered() != 0 || !term.IsTerminal(int(f.Fd())) {
+		return "", false, nil
+	}
+	b, err := term.ReadPassword(int(f.Fd()))
+	if err != nil {
+		return "", false, err
+	}
+	return string(b), true, nil
+}
+
+// 读取一行输入，不包含换行符，最后一行可以没有换行符
+func readLine(r *bufio.Reader) (string, error) {
+	line, err := r.ReadString('\n')
+	if err == io.EOF && line != "" {
+		err = nil
+	}
+	if err == io.EOF {
+		return "", io.ErrUnexpectedEOF
+	}
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimRight(line, "\r\n"), nil
+}
+
+func optionType(opt project.Option) string {
+	if opt.Type == project.OptionList {
+		return "list of " + opt.Item + ", separated by comma"
+	}
+	return opt.Type
+}
+
+func bound(f *float64) string {
+	if f == nil {
+		return ""
+	}
+	return fmt.Sprint(*f)
+}
+
+// list 显示为逗号分隔的值，与输入的格式相同
+func format(v any) string {
+	items, ok := v.([]any)
+	if !ok {
+		return fmt.Sprint(v)
+	}
+	s := make([]string, 0, len(items))
+	for _, i := range items {
+		s = append(s, fmt.Sprint(i))
+	}
+	return strings.Join(s, ", ")
+}
@@ -2,7 +2,10 @@ package cv
 
 import (
 	"errors"
+	"fmt"
 	"image"
+	"image/color"
+	"image/draw"
 
 	"gocv.io/x/gocv"
 )
@@ -10,26 +13,147 @@ import (
 var ErrIMEmpty = errors.New("empty image")
 var ErrVTooLow = errors.New("value too low")
 
+// 模板匹配前对图像的预处理方式
+type ColorMode int
+
+const (
+	// 使用 BGR 三个通道，透明通道会被丢弃
+	ColorModeColor ColorMode = iota
+	ColorModeGray
+	// 只使用 HSV 中的一个通道
+	ColorModeHSVH
+	ColorModeHSVS
+	ColorModeHSVV
+	// 灰度图经过 Canny 边缘检测，适用于颜色会变化但轮廓不变的目标
+	ColorModeCanny
+)
+
+// 模板匹配的选项
+// Method 只支持归一化的方法：TmSqdiffNormed, TmCcorrNormed, TmCcoeffNormed
+// Blur 是匹配前高斯模糊的核大小，必须是奇数，为 0 时不进行模糊
+type MatchOption struct {
+	Method gocv.TemplateMatchMode
+	Color  ColorMode
+	Blur   int
+}
+
+// Find 使用的默认选项
+var DefaultMatchOption = MatchOption{
+	Method: gocv.TmCcoeffNormed,
+	Color:  ColorModeColor,
+}
+
 // 使用模板匹配在 img 中匹配 tmpl
 // 第一个返回值是 maxVal，第二个返回值是 maxLoc
 func Find(img, tmpl gocv.Mat) (float32, image.Point, error) {
-	grayImg := gocv.NewMat()
-	grayTmpl := gocv.NewMat()
+	return FindWithOption(img, tmpl, DefaultMatchOption)
+}
+
+// 使用指定的选项进行模板匹配，返回值与 Find 相同
+// 对于 TmSqdiffNormed，返回的值是 1 - minVal，因此总是值越大越匹配
+func FindWithOption(img, tmpl gocv.Mat, opt MatchOption) (float32, image.Point, error) {
+	if img.Empty() || tmpl.Empty() {
+		return 0, image.ZP, ErrIMEmpty
+	}
+	switch opt.Method {
+	case gocv.TmSqdiffNormed, gocv.TmCcorrNormed, gocv.TmCcoeffNormed:
+	default:
+		return 0, image.ZP, fmt.Errorf("unsupported match method [%d]", opt.Method)
+	}
+	if opt.Blur < 0 || (opt.Blur != 0 && opt.Blur%2 == 0) {
+		return 0, image.ZP, fmt.Errorf("blur [%d] must be 0 or a positive odd number", opt.Blur)
+	}
+	pImg := gocv.NewMat()
+	pTmpl := gocv.NewMat()
 	result := gocv.NewMat()
 	mask := gocv.NewMat()
 	defer func() {
-		grayImg.Close()
-		grayTmpl.Close()
+		pImg.Close()
+		pTmpl.Close()
 		result.Close()
 		mask.Close()
 	}()
+	err := preprocess(img, &pImg, opt)
+	if err != nil {
+		return 0, image.ZP, err
+	}
+	err = preprocess(tmpl, &pTmpl, opt)
+	if err != nil {
+		return 0, image.ZP, err
+	}
+	gocv.MatchTemplate(pImg, pTmpl, &result, opt.Method, mask)
 
-	// 将图像转换为RGBA图像
-	gocv.CvtColor(img, &grayImg, gocv.ColorBGRToRGBA)
-	gocv.CvtColor(tmpl, &grayTmpl, gocv.ColorBGRToRGBA)
-	gocv.MatchTemplate(grayImg, grayTmpl, &result, gocv.TmCcoeffNormed, mask)
+	minVal, maxVal, minLoc, maxLoc := gocv.MinMaxLoc(result)
+	if opt.Method == gocv.TmSqdiffNormed {
+		return 1 - minVal, minLoc, nil
+	}
+	return maxVal, maxLoc, nil
+}
 
-	_, maxVal, _, maxLoc := gocv.MinMaxLoc(result)
+// 按照 opt 将 src 转换成用于模板匹配的图像
+func preprocess(src gocv.Mat, dst *gocv.Mat, opt MatchOption) error {
+	bgr := gocv.NewMat()
+	defer bgr.Close()
+	switch src.Channels() {
+	case 4:
+		gocv.CvtColor(src, &bgr, gocv.ColorBGRAToBGR)
+	case 3:
+		src.CopyTo(&bgr)
+	case 1:
+		gocv.CvtColor(src, &bgr, gocv.ColorGrayToBGR)
+	default:
+		return fmt.Errorf("unsupported channels [%d]", src.Channels())
+	}
+	if opt.Blur > 0 {
+		gocv.GaussianBlur(bgr, &bgr, image.Pt(opt.Blur, opt.Blur), 0, 0, gocv.BorderDefault)
+	}
+	switch opt.Color {
+	case ColorModeColor:
+		bgr.CopyTo(dst)
+	case ColorModeGray:
+		gocv.CvtColor(bgr, dst, gocv.ColorBGRToGray)
+	case ColorModeHSVH, ColorModeHSVS, ColorModeHSVV:
+		hsv := gocv.NewMat()
+		defer hsv.Close()
+		gocv.CvtColor(bgr, &hsv, gocv.ColorBGRToHSV)
+		gocv.ExtractChannel(hsv, dst, int(opt.Color-ColorModeHSVH))
+	case ColorModeCanny:
+		gray := gocv.NewMat()
+		defer gray.Close()
+		gocv.CvtColor(bgr, &gray, gocv.ColorBGRToGray)
+		gocv.Canny(gray, dst, 50, 150)
+	default:
+		return fmt.Errorf("unknown color mode [%d]", opt.Color)
+	}
+	return nil
+}
 
-	return maxVal, maxLoc, nil
+// 裁剪 img 中 r 范围内的图像，r 会被限制在 img 的范围内。
+// 实现了 SubImage 方法的图像（image.RGBA、image.YCbCr 等）不会复制像素，
+// 其他类型的图像会被复制到一个新的 image.RGBA 中
+func SubImage(img image.Image, r image.Rectangle) image.Image {
+	r = r.Canon().Intersect(img.Bounds())
+	if s, ok := img.(interface {
+		SubImage(image.Rectangle) image.Image
+	}); ok {
+		return s.SubImage(r)
+	}
+	dst := image.NewRGBA(r)
+	draw.Draw(dst, r, img, r.Min, draw.Src)
+	return dst
+}
+
+// 判断 a 和 b 两个颜色是否相近，tolerance 是每个颜色通道 (R, G, B) 允许的误差
+func ColorMatch(a, b color.Color, tolerance int) bool {
+	ca := color.RGBAModel.Convert(a).(color.RGBA)
+	cb := color.RGBAModel.Convert(b).(color.RGBA)
+	diff := func(x, y uint8) int {
+		if x > y {
+			return int(x - y)
+		}
+		return int(y - x)
+	}
+	return diff(ca.R, cb.R) <= tolerance &&
+		diff(ca.G, cb.G) <= tolerance &&
+		diff(ca.B, cb.B) <= tolerance
 }
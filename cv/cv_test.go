@@ -1,8 +1,10 @@
 package cv_test
 
 import (
+	"errors"
 	"fmt"
 	"image"
+	"image/color"
 	"testing"
 
 	"github.com/HumXC/give-me-time/cv"
@@ -76,9 +78,71 @@ func TestFind(t *testing.T) {
 	}
 }
 
+func TestFindFeature(t *testing.T) {
+	// small.png 在 big.png 中的中心位置
+	want := image.Point{
+		X: 103 + 107/2,
+		Y: 174 + 26/2,
+	}
+	big := gocv.IMRead("test/big.png", gocv.IMReadUnchanged)
+	small := gocv.IMRead("test/small.png", gocv.IMReadUnchanged)
+	defer big.Close()
+	defer small.Close()
+	for _, f := range []cv.Feature{cv.FeatureORB, cv.FeatureAKAZE} {
+		v, p, err := cv.FindFeature(big, small, f)
+		if err != nil {
+			t.Fatal(err)
+		}
+		d := p.Sub(want)
+		if v <= 0 || d.X*d.X+d.Y*d.Y > 25 {
+			t.Errorf("feature [%d] want: %v, got: %v (%f)", f, want, p, v)
+		}
+	}
+}
+
+func TestFindFeatureTooSmall(t *testing.T) {
+	big := gocv.IMRead("test/big.png", gocv.IMReadUnchanged)
+	small := gocv.IMRead("test/small.png", gocv.IMReadUnchanged)
+	defer big.Close()
+	defer small.Close()
+	// 只有 small.png 的左上角 10x10
+	tiny := small.Region(image.Rect(0, 0, 10, 10))
+	defer tiny.Close()
+	for _, f := range []cv.Feature{cv.FeatureORB, cv.FeatureAKAZE} {
+		_, _, err := cv.FindFeature(big, tiny, f)
+		if !errors.Is(err, cv.ErrTmplTooSmall) {
+			t.Errorf("feature [%d] want: %v, got: %v", f, cv.ErrTmplTooSmall, err)
+		}
+	}
+}
+
+func TestFindFeaturePoints(t *testing.T) {
+	big := gocv.IMRead("test/big.png", gocv.IMReadUnchanged)
+	small := gocv.IMRead("test/small.png", gocv.IMReadUnchanged)
+	defer big.Close()
+	defer small.Close()
+	// small.png 的左上角和右下角在 big.png 中的位置
+	want := []image.Point{{X: 103, Y: 174}, {X: 103 + 107, Y: 174 + 26}}
+	for _, f := range []cv.Feature{cv.FeatureORB, cv.FeatureAKAZE} {
+		v, ps, err := cv.FindFeaturePoints(big, small, f, []image.Point{{}, {X: 107, Y: 26}})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if v <= 0 || len(ps) != len(want) {
+			t.Fatalf("feature [%d] want: %v, got: %v (%f)", f, want, ps, v)
+		}
+		for i := range want {
+			d := ps[i].Sub(want[i])
+			if d.X*d.X+d.Y*d.Y > 25 {
+				t.Errorf("feature [%d] want: %v, got: %v", f, want[i], ps[i])
+			}
+		}
+	}
+}
+
 func BenchmarkFind(b *testing.B) {
-	big := gocv.IMRead("../test/big.png", gocv.IMReadUnchanged)
-	small := gocv.IMRead("../test/small.png", gocv.IMReadUnchanged)
+	big := gocv.IMRead("test/big.png", gocv.IMReadUnchanged)
+	small := gocv.IMRead("test/small.png", gocv.IMReadUnchanged)
 	for i := 0; i < b.N; i++ {
 		_, _, err := cv.Find(big, small)
 		if err != nil {
@@ -86,3 +150,128 @@ func BenchmarkFind(b *testing.B) {
 		}
 	}
 }
+
+// 对比不同匹配方法和预处理方式的速度和准确度
+// score 是匹配的值，hit 为 1 表示匹配到了正确的位置
+func BenchmarkFindWithOption(b *testing.B) {
+	want := image.Point{
+		X: 103,
+		Y: 174,
+	}
+	big := gocv.IMRead("test/big.png", gocv.IMReadUnchanged)
+	small := gocv.IMRead("test/small.png", gocv.IMReadUnchanged)
+	defer big.Close()
+	defer small.Close()
+	methods := map[string]gocv.TemplateMatchMode{
+		"SqdiffNormed": gocv.TmSqdiffNormed,
+		"CcorrNormed":  gocv.TmCcorrNormed,
+		"CcoeffNormed": gocv.TmCcoeffNormed,
+	}
+	colors := map[string]cv.ColorMode{
+		"Color": cv.ColorModeColor,
+		"Gray":  cv.ColorModeGray,
+		"HSVH":  cv.ColorModeHSVH,
+		"HSVV":  cv.ColorModeHSVV,
+		"Canny": cv.ColorModeCanny,
+	}
+	for mName, m := range methods {
+		for cName, c := range colors {
+			for _, blur := range []int{0, 3} {
+				opt := cv.MatchOption{Method: m, Color: c, Blur: blur}
+				b.Run(fmt.Sprintf("%s/%s/Blur%d", mName, cName, blur), func(b *testing.B) {
+					var v float32
+					var p image.Point
+					var err error
+					for i := 0; i < b.N; i++ {
+						v, p, err = cv.FindWithOption(big, small, opt)
+						if err != nil {
+							b.Fatal(err)
+						}
+					}
+					hit := 0.0
+					if p.Eq(want) {
+						hit = 1
+					}
+					b.ReportMetric(float64(v), "score")
+					b.ReportMetric(hit, "hit")
+				})
+			}
+		}
+	}
+}
+
+func TestFindWithOption(t *testing.T) {
+	want := image.Point{
+		X: 103,
+		Y: 174,
+	}
+	big := gocv.IMRead("test/big.png", gocv.IMReadUnchanged)
+	small := gocv.IMRead("test/small.png", gocv.IMReadUnchanged)
+	defer big.Close()
+	defer small.Close()
+	opts := []cv.MatchOption{
+		{Method: gocv.TmSqdiffNormed, Color: cv.ColorModeColor},
+		{Method: gocv.TmCcoeffNormed, Color: cv.ColorModeGray},
+		{Method: gocv.TmCcoeffNormed, Color: cv.ColorModeColor, Blur: 3},
+	}
+	for i, opt := range opts {
+		_, p, err := cv.FindWithOption(big, small, opt)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !p.Eq(want) {
+			t.Errorf("case [%d] want: %v, got: %v", i, want, p)
+		}
+	}
+	bads := []cv.MatchOption{
+		{Method: gocv.TmSqdiff},
+		{Method: gocv.TmCcoeffNormed, Blur: 2},
+		{Method: gocv.TmCcoeffNormed, Color: 100},
+	}
+	for i, opt := range bads {
+		_, _, err := cv.FindWithOption(big, small, opt)
+		if err == nil {
+			t.Errorf("case [%d] should be an error, but not", i)
+		}
+	}
+}
+
+func TestSubImage(t *testing.T) {
+	r := image.Rect(10, 20, 30, 40)
+	imgs := []image.Image{
+		image.NewRGBA(image.Rect(0, 0, 100, 100)),
+		image.NewYCbCr(image.Rect(0, 0, 100, 100), image.YCbCrSubsampleRatio420),
+		image.NewUniform(image.Black),
+	}
+	for i, img := range imgs {
+		sub := cv.SubImage(img, r)
+		if !sub.Bounds().Eq(r) {
+			t.Errorf("case [%d] want: %v, got: %v", i, r, sub.Bounds())
+		}
+	}
+	// 超出范围的部分会被裁掉
+	sub := cv.SubImage(imgs[0], image.Rect(90, 90, 120, 120))
+	if want := image.Rect(90, 90, 100, 100); !sub.Bounds().Eq(want) {
+		t.Errorf("want: %v, got: %v", want, sub.Bounds())
+	}
+}
+
+func TestColorMatch(t *testing.T) {
+	red := color.RGBA{R: 250, G: 10, B: 10, A: 255}
+	cases := []struct {
+		c         color.Color
+		tolerance int
+		want      bool
+	}{
+		{color.RGBA{R: 250, G: 10, B: 10, A: 255}, 0, true},
+		{color.RGBA{R: 255, G: 0, B: 0, A: 255}, 10, true},
+		{color.RGBA{R: 255, G: 0, B: 0, A: 255}, 9, false},
+		{color.NRGBA{R: 240, G: 20, B: 0, A: 255}, 10, true},
+		{color.Gray{Y: 128}, 100, false},
+	}
+	for i, c := range cases {
+		if got := cv.ColorMatch(red, c.c, c.tolerance); got != c.want {
+			t.Errorf("case [%d] want: %t, got: %t", i, c.want, got)
+		}
+	}
+}
@@ -0,0 +1,169 @@
+package cv
+
+import (
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+
+	"gocv.io/x/gocv"
+)
+
+// 特征点检测的算法
+type Feature int
+
+const (
+	FeatureORB Feature = iota
+	FeatureAKAZE
+)
+
+// Lowe's ratio test 的比例，最近邻与次近邻的距离之比小于该值才被认为是可靠的匹配
+const featureRatio = 0.75
+
+// 计算单应性矩阵至少需要 4 对点
+const featureMinMatch = 4
+
+// ORB 的 edgeThreshold 和 patchSize，默认的 31 在小于约 62px 的模板上找不到特征点
+// 模板会先在四周填充 orbBorder 的边框，使靠近边缘的特征点也能被检测到
+const (
+	orbPatchSize = 15
+	orbBorder    = orbPatchSize
+	orbFeatures  = 3000
+)
+
+// 模板的宽和高都不能小于 FeatureMinSize
+const FeatureMinSize = 16
+
+// 模板太小或者特征点不足 featureMinMatch 个，不能使用特征点匹配
+var ErrTmplTooSmall = errors.New("template too small for feature matching")
+
+// 使用特征点匹配在 img 中查找 tmpl，适用于旋转、缩放或者部分被遮挡的目标
+// 第一个返回值是置信度（RANSAC 内点占可靠匹配的比例），第二个返回值是 tmpl 的中心在 img 中的位置
+// 可靠的匹配不足时置信度为 0，tmpl 太小或者特征点不足时返回 ErrTmplTooSmall
+func FindFeature(img, tmpl gocv.Mat, feature Feature) (float32, image.Point, error) {
+	center := image.Pt(tmpl.Cols()/2, tmpl.Rows()/2)
+	v, ps, err := FindFeaturePoints(img, tmpl, feature, []image.Point{center})
+	if err != nil || v == 0 {
+		return v, image.ZP, err
+	}
+	return v, ps[0], nil
+}
+
+// 与 FindFeature 相同，但是返回 tmpl 上的 points 通过单应性矩阵投影到 img 上的位置
+// 目标被旋转或者缩放时，tmpl 上的每个点都需要单独投影
+// 置信度为 0 时返回的位置为 nil
+func FindFeaturePoints(img, tmpl gocv.Mat, feature Feature, points []image.Point) (float32, []image.Point, error) {
+	if img.Empty() || tmpl.Empty() {
+		return 0, nil, ErrIMEmpty
+	}
+	if tmpl.Cols() < FeatureMinSize || tmpl.Rows() < FeatureMinSize {
+		return 0, nil, fmt.Errorf("%w: %dx%d, at least %dx%d", ErrTmplTooSmall, tmpl.Cols(), tmpl.Rows(), FeatureMinSize, FeatureMinSize)
+	}
+	grayImg := gocv.NewMat()
+	grayTmpl := gocv.NewMat()
+	mask := gocv.NewMat()
+	defer func() {
+		grayImg.Close()
+		grayTmpl.Close()
+		mask.Close()
+	}()
+	toGray(img, &grayImg)
+	toGray(tmpl, &grayTmpl)
+
+	var detect func(gocv.Mat, gocv.Mat) ([]gocv.KeyPoint, gocv.Mat)
+	// 模板四周填充的边框，模板上的特征点需要减去 border
+	border := 0
+	switch feature {
+	case FeatureORB:
+		orb := gocv.NewORBWithParams(orbFeatures, 1.2, 8, orbPatchSize, 0, 2, gocv.ORBScoreTypeHarris, orbPatchSize, 20)
+		defer orb.Close()
+		detect = orb.DetectAndCompute
+		border = orbBorder
+		padded := gocv.NewMat()
+		defer padded.Close()
+		gocv.CopyMakeBorder(grayTmpl, &padded, border, border, border, border, gocv.BorderReplicate, color.RGBA{})
+		padded.CopyTo(&grayTmpl)
+	case FeatureAKAZE:
+		akaze := gocv.NewAKAZE()
+		defer akaze.Close()
+		detect = akaze.DetectAndCompute
+	default:
+		return 0, nil, fmt.Errorf("unknown feature [%d]", feature)
+	}
+	kpTmpl, descTmpl := detect(grayTmpl, mask)
+	defer descTmpl.Close()
+	kpImg, descImg := detect(grayImg, mask)
+	defer descImg.Close()
+	if descTmpl.Empty() || len(kpTmpl) < featureMinMatch {
+		return 0, nil, fmt.Errorf("%w: only %d feature points", ErrTmplTooSmall, len(kpTmpl))
+	}
+	if descImg.Empty() {
+		return 0, nil, nil
+	}
+
+	// ORB 和 AKAZE 的描述子都是二进制的，使用汉明距离
+	matcher := gocv.NewBFMatcherWithParams(gocv.NormHamming, false)
+	defer matcher.Close()
+	good := make([]gocv.DMatch, 0)
+	for _, m := range matcher.KnnMatch(descTmpl, descImg, 2) {
+		if len(m) == 2 && m[0].Distance < featureRatio*m[1].Distance {
+			good = append(good, m[0])
+		}
+	}
+	if len(good) < featureMinMatch {
+		return 0, nil, nil
+	}
+
+	src := gocv.NewMatWithSize(len(good), 1, gocv.MatTypeCV32FC2)
+	dst := gocv.NewMatWithSize(len(good), 1, gocv.MatTypeCV32FC2)
+	inliers := gocv.NewMat()
+	defer func() {
+		src.Close()
+		dst.Close()
+		inliers.Close()
+	}()
+	for i, m := range good {
+		src.SetFloatAt(i, 0, float32(kpTmpl[m.QueryIdx].X)-float32(border))
+		src.SetFloatAt(i, 1, float32(kpTmpl[m.QueryIdx].Y)-float32(border))
+		dst.SetFloatAt(i, 0, float32(kpImg[m.TrainIdx].X))
+		dst.SetFloatAt(i, 1, float32(kpImg[m.TrainIdx].Y))
+	}
+	h := gocv.FindHomography(src, &dst, gocv.HomograpyMethodRANSAC, 3, &inliers, 2000, 0.995)
+	defer h.Close()
+	if h.Empty() {
+		return 0, nil, nil
+	}
+
+	// 将 tmpl 上的点投影到 img 上
+	pts := gocv.NewMatWithSize(len(points), 1, gocv.MatTypeCV32FC2)
+	result := gocv.NewMat()
+	defer func() {
+		pts.Close()
+		result.Close()
+	}()
+	for i, p := range points {
+		pts.SetFloatAt(i, 0, float32(p.X))
+		pts.SetFloatAt(i, 1, float32(p.Y))
+	}
+	gocv.PerspectiveTransform(pts, &result, h)
+	ps := make([]image.Point, len(points))
+	for i := range points {
+		v := result.GetVecfAt(i, 0)
+		ps[i] = image.Pt(int(v[0]), int(v[1]))
+	}
+
+	confidence := float32(gocv.CountNonZero(inliers)) / float32(len(good))
+	return confidence, ps, nil
+}
+
+// 将 src 转换成灰度图，src 可以是灰度、BGR 或者 BGRA 图像
+func toGray(src gocv.Mat, dst *gocv.Mat) {
+	switch src.Channels() {
+	case 4:
+		gocv.CvtColor(src, dst, gocv.ColorBGRAToGray)
+	case 3:
+		gocv.CvtColor(src, dst, gocv.ColorBGRToGray)
+	default:
+		src.CopyTo(dst)
+	}
+}
@@ -0,0 +1,68 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"text/tabwriter"
+
+	"github.com/HumXC/give-me-time/devices"
+)
+
+// devices [-json]：列出所有设备的信息
+// devices connect <host:port>：连接无线设备，并保存到已知设备中
+// devices pair <host:port> <code>：与无线设备配对
+// devices disconnect <host:port>：断开无线设备，并从已知设备中移除
+func runDevices(args []string) error {
+	_adb := devices.NewADB(adbPath)
+	if len(args) != 0 {
+		switch args[0] {
+		case "connect":
+			if len(args) != 2 {
+				return fmt.Errorf("usage: devices connect <host:port>")
+			}
+			return _adb.Connect(args[1])
+		case "pair":
+			if len(args) != 3 {
+				return fmt.Errorf("usage: devices pair <host:port> <code>")
+			}
+			return _adb.Pair(args[1], args[2])
+		case "disconnect":
+			if len(args) != 2 {
+				return fmt.Errorf("usage: devices disconnect <host:port>")
+			}
+			return _adb.Disconnect(args[1])
+		}
+	}
+	fs := flag.NewFlagSet("devices", flag.ContinueOnError)
+	asJSON := fs.Bool("json", false, "以 json 格式输出")
+	err := fs.Parse(args)
+	if err != nil {
+		return err
+	}
+	ds, err := _adb.Devices()
+	if err != nil {
+		return err
+	}
+	infos := make([]devices.DeviceInfo, 0, len(ds))
+	for _, d := range ds {
+		info, err := devices.Info(d)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "%s: %v\n", d.ID, err)
+		}
+		infos = append(infos, info)
+	}
+	if *asJSON {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "    ")
+		return enc.Encode(infos)
+	}
+	w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "ID\tONLINE\tPRODUCT\tMODEL\tSCREEN\tDENSITY\tANDROID\tABI")
+	for _, i := range infos {
+		fmt.Fprintf(w, "%s\t%t\t%s\t%s\t%dx%d\t%d\t%s\t%s\n",
+			i.ID, i.IsOnline, i.Product, i.Model, i.Width, i.Height, i.Density, i.AndroidVersion, i.ABI)
+	}
+	return w.Flush()
+}
@@ -2,12 +2,20 @@ package devices
 
 import (
 	"errors"
+	"fmt"
+	"image"
+	"strconv"
+	"strings"
 
 	"github.com/HumXC/adb-helper"
 )
 
+// knownFile 是保存已知无线设备的文件，为空时不保存
 type ADB struct {
-	server adb.Server
+	server    adb.Server
+	cmd       adb.ADBRunner
+	path      string
+	knownFile string
 }
 
 func (a *ADB) FirstDevice() (*adb.Device, error) {
@@ -20,17 +28,81 @@ func (a *ADB) FirstDevice() (*adb.Device, error) {
 	}
 	return nil, errors.New("no device")
 }
+
+// 返回所有设备，包括离线的设备
+func (a *ADB) Devices() ([]adb.Device, error) {
+	return a.server.Devices()
+}
+
+// 返回所有设备的 ID
 func (a *ADB) List() ([]string, error) {
 	ds, err := a.server.Devices()
 	if err != nil {
 		return nil, err
 	}
-	result := make([]string, len(ds), len(ds))
+	result := make([]string, 0, len(ds))
 	for _, d := range ds {
 		result = append(result, d.ID)
 	}
 	return result, nil
 }
+
+// 根据 spec 选择设备，参考 FilterDevices
+func (a *ADB) SelectDevices(spec string) ([]adb.Device, error) {
+	ds, err := a.server.Devices()
+	if err != nil {
+		return nil, err
+	}
+	return FilterDevices(ds, spec)
+}
+
+// 根据 spec 从 ds 中选择设备：
+//   - 空字符串：第一个设备
+//   - "all"：所有在线的设备
+//   - 逗号分隔的设备 ID，例如 "emulator-5554,192.168.1.2:5555"
+func FilterDevices(ds []adb.Device, spec string) ([]adb.Device, error) {
+	spec = strings.TrimSpace(spec)
+	switch spec {
+	case "":
+		if len(ds) == 0 {
+			return nil, errors.New("no device")
+		}
+		return ds[:1], nil
+	case "all":
+		result := make([]adb.Device, 0, len(ds))
+		for _, d := range ds {
+			if d.IsOnline {
+				result = append(result, d)
+			}
+		}
+		if len(result) == 0 {
+			return nil, errors.New("no online device")
+		}
+		return result, nil
+	}
+	result := make([]adb.Device, 0)
+	seen := make(map[string]struct{})
+	for _, id := range strings.Split(spec, ",") {
+		id = strings.TrimSpace(id)
+		if _, ok := seen[id]; ok || id == "" {
+			continue
+		}
+		seen[id] = struct{}{}
+		found := false
+		for _, d := range ds {
+			if d.ID == id {
+				result = append(result, d)
+				found = true
+				break
+			}
+		}
+		if !found {
+			return nil, errors.New(id + " not found")
+		}
+	}
+	return result, nil
+}
+
 func (a *ADB) GetDevice(id string) (*adb.Device, error) {
 	ds, err := a.server.Devices()
 	if err != nil {
@@ -46,8 +118,137 @@ func (a *ADB) GetDevice(id string) (*adb.Device, error) {
 	}
 	return nil, errors.New(id + " not found")
 }
+
+// 通过 "wm size" 获取设备屏幕的分辨率，设置了 Override size 时优先使用它
+func ScreenSize(device adb.Device) (image.Point, error) {
+	out, err := device.Cmd("shell wm size")
+	if err != nil {
+		return image.ZP, fmt.Errorf("adb error: %w", err)
+	}
+	return ParseWmSize(string(out))
+}
+
+// 解析 "wm size" 的输出，例如：
+//
+//	Physical size: 1080x2400
+//	Override size: 720x1600
+func ParseWmSize(out string) (image.Point, error) {
+	var physical, override image.Point
+	for _, line := range strings.Split(out, "\n") {
+		k, v, ok := strings.Cut(strings.TrimSpace(line), ":")
+		if !ok {
+			continue
+		}
+		var p image.Point
+		_, err := fmt.Sscanf(strings.TrimSpace(v), "%dx%d", &p.X, &p.Y)
+		if err != nil {
+			continue
+		}
+		switch k {
+		case "Physical size":
+			physical = p
+		case "Override size":
+			override = p
+		}
+	}
+	if !override.Eq(image.ZP) {
+		return override, nil
+	}
+	if !physical.Eq(image.ZP) {
+		return physical, nil
+	}
+	return image.ZP, fmt.Errorf("can not parse screen size from [%s]", strings.TrimSpace(out))
+}
+
+// 设备的信息，离线的设备只有 ID, IsOnline, Product 和 Model
+type DeviceInfo struct {
+	ID             string `json:"id"`
+	IsOnline       bool   `json:"is_online"`
+	Product        string `json:"product"`
+	Model          string `json:"model"`
+	Width          int    `json:"width"`
+	Height         int    `json:"height"`
+	Density        int    `json:"density"`
+	AndroidVersion string `json:"android_version"`
+	ABI            string `json:"abi"`
+}
+
+// 获取设备的信息，查询失败时返回已经获取到的部分和错误
+func Info(device adb.Device) (DeviceInfo, error) {
+	info := DeviceInfo{
+		ID:       device.ID,
+		IsOnline: device.IsOnline,
+		Product:  device.Product,
+		Model:    device.Model,
+	}
+	if !device.IsOnline {
+		return info, nil
+	}
+	size, err := ScreenSize(device)
+	if err != nil {
+		return info, err
+	}
+	info.Width, info.Height = size.X, size.Y
+	out, err := device.Cmd("shell wm density")
+	if err != nil {
+		return info, fmt.Errorf("adb error: %w", err)
+	}
+	info.Density, err = ParseWmDensity(string(out))
+	if err != nil {
+		return info, err
+	}
+	out, err = device.Cmd("shell getprop ro.build.version.release")
+	if err != nil {
+		return info, fmt.Errorf("adb error: %w", err)
+	}
+	info.AndroidVersion = strings.TrimSpace(string(out))
+	out, err = device.Cmd("shell getprop ro.product.cpu.abi")
+	if err != nil {
+		return info, fmt.Errorf("adb error: %w", err)
+	}
+	info.ABI = strings.TrimSpace(string(out))
+	return info, nil
+}
+
+// 解析 "wm density" 的输出，设置了 Override density 时优先使用它，例如：
+//
+//	Physical density: 440
+//	Override density: 400
+func ParseWmDensity(out string) (int, error) {
+	var physical, override int
+	for _, line := range strings.Split(out, "\n") {
+		k, v, ok := strings.Cut(strings.TrimSpace(line), ":")
+		if !ok {
+			continue
+		}
+		d, err := strconv.Atoi(strings.TrimSpace(v))
+		if err != nil {
+			continue
+		}
+		switch k {
+		case "Physical density":
+			physical = d
+		case "Override density":
+			override = d
+		}
+	}
+	if override != 0 {
+		return override, nil
+	}
+	if physical != 0 {
+		return physical, nil
+	}
+	return 0, fmt.Errorf("can not parse density from [%s]", strings.TrimSpace(out))
+}
+
+// 已知的无线设备保存在 DefaultKnownFile 中
 func NewADB(adbPath string) ADB {
+	runner := adb.NewADBRunner(adbPath)
+	knownFile, _ := DefaultKnownFile()
 	return ADB{
-		server: adb.NewServer(adb.NewADBRunner(adbPath), adbPath),
+		server:    adb.NewServer(runner, adbPath),
+		cmd:       runner,
+		path:      adbPath,
+		knownFile: knownFile,
 	}
 }
@@ -0,0 +1,121 @@
+package devices_test
+
+import (
+	"image"
+	"strings"
+	"testing"
+
+	"github.com/HumXC/adb-helper"
+	"github.com/HumXC/give-me-time/devices"
+)
+
+func TestParseWmSize(t *testing.T) {
+	goods := map[string]image.Point{
+		"Physical size: 1080x2400\n":                              image.Pt(1080, 2400),
+		"Physical size: 1080x2400\r\nOverride size: 720x1600\r\n": image.Pt(720, 1600),
+	}
+	for out, want := range goods {
+		got, err := devices.ParseWmSize(out)
+		if err != nil {
+			t.Errorf("case [%q]: %v", out, err)
+			continue
+		}
+		if !got.Eq(want) {
+			t.Errorf("case [%q] want: %v, got: %v", out, want, got)
+		}
+	}
+	bads := []string{"", "error: no devices/emulators found", "Physical size: unknown"}
+	for _, out := range bads {
+		_, err := devices.ParseWmSize(out)
+		if err == nil {
+			t.Errorf("case [%q] should be an error, but not", out)
+		}
+	}
+}
+
+func TestFilterDevices(t *testing.T) {
+	ds := []adb.Device{
+		{ID: "a", IsOnline: true},
+		{ID: "b", IsOnline: false},
+		{ID: "192.168.1.2:5555", IsOnline: true},
+	}
+	goods := map[string][]string{
+		"":                      {"a"},
+		"all":                   {"a", "192.168.1.2:5555"},
+		"b":                     {"b"},
+		"192.168.1.2:5555, a,a": {"192.168.1.2:5555", "a"},
+	}
+	for spec, want := range goods {
+		got, err := devices.FilterDevices(ds, spec)
+		if err != nil {
+			t.Errorf("case [%s]: %v", spec, err)
+			continue
+		}
+		ids := make([]string, 0, len(got))
+		for _, d := range got {
+			ids = append(ids, d.ID)
+		}
+		if strings.Join(ids, ",") != strings.Join(want, ",") {
+			t.Errorf("case [%s] want: %v, got: %v", spec, want, ids)
+		}
+	}
+	bads := map[string][]adb.Device{
+		"c":   ds,
+		"a,c": ds,
+		"":    nil,
+		"all": {{ID: "b"}},
+	}
+	for spec, ds := range bads {
+		_, err := devices.FilterDevices(ds, spec)
+		if err == nil {
+			t.Error("case [" + spec + "] should be an error, but not")
+		}
+	}
+}
+
+func TestParseWmDensity(t *testing.T) {
+	goods := map[string]int{
+		"Physical density: 440\n":                            440,
+		"Physical density: 440\r\nOverride density: 400\r\n": 400,
+	}
+	for out, want := range goods {
+		got, err := devices.ParseWmDensity(out)
+		if err != nil || got != want {
+			t.Errorf("case [%q] want: %d, got: %d, %v", out, want, got, err)
+		}
+	}
+	bads := []string{"", "Physical density: unknown"}
+	for _, out := range bads {
+		_, err := devices.ParseWmDensity(out)
+		if err == nil {
+			t.Errorf("case [%q] should be an error, but not", out)
+		}
+	}
+}
+
+func TestInfo(t *testing.T) {
+	outs := map[string]string{
+		"shell wm size":                          "Physical size: 1080x2400\n",
+		"shell wm density":                       "Physical density: 440\n",
+		"shell getprop ro.build.version.release": "13\n",
+		"shell getprop ro.product.cpu.abi":       "arm64-v8a\n",
+	}
+	d := adb.Device{ID: "a", IsOnline: true, Model: "Pixel", Cmd: func(cmd string) ([]byte, error) {
+		return []byte(outs[cmd]), nil
+	}}
+	info, err := devices.Info(d)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := devices.DeviceInfo{ID: "a", IsOnline: true, Model: "Pixel", Width: 1080, Height: 2400, Density: 440, AndroidVersion: "13", ABI: "arm64-v8a"}
+	if info != want {
+		t.Errorf("want: %+v, got: %+v", want, info)
+	}
+	// 离线的设备不会执行命令
+	d.IsOnline = false
+	d.Cmd = nil
+	_, err = devices.Info(d)
+	if err != nil {
+		t.Error(err)
+	}
+}
@@ -0,0 +1,144 @@
+package devices
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"image"
+	"strings"
+
+	"github.com/HumXC/adb-helper"
+)
+
+// 常用的按键，使用 Android 的 keycode 名称，其他后端需要把它们转换为自己的按键
+const (
+	KeyBack  = "KEYCODE_BACK"
+	KeyHome  = "KEYCODE_HOME"
+	KeyEnter = "KEYCODE_ENTER"
+)
+
+// Device 是一个可以截图和输入的设备，api 层只依赖这个接口
+// ADBDevice 是第一个后端，模拟器，scrcpy 或者测试用的假设备只需要实现这个接口
+type Device interface {
+	// 设备的唯一标识，用于日志和重连
+	ID() string
+	// 获取屏幕的截图，返回编码后的图片，例如 png 或者 jpeg
+	Screenshot() ([]byte, error)
+	// 按下一个坐标，duration 单位是 ms
+	Tap(x, y, duration int) error
+	// 从 (x1, y1) 滑动到 (x2, y2)，duration 单位是 ms
+	Swipe(x1, y1, x2, y2, duration int) error
+	// 按下一个按键，例如 KeyBack
+	Key(key string) error
+	// 在设备上执行 shell 命令，不支持时返回 ErrUnsupported
+	Shell(cmd string) ([]byte, error)
+	// 屏幕的分辨率
+	ScreenSize() (image.Point, error)
+}
+
+// 设备不支持的操作返回的错误
+var ErrUnsupported = errors.New("unsupported")
+
+// 可以重启应用的设备，用于 Flow 的 restart 动作
+type Restarter interface {
+	// 结束并重新启动应用 app，对于 Android 设备 app 是包名
+	Restart(app string) error
+}
+
+// 使用之前需要初始化的设备
+type Initializer interface {
+	Init() error
+}
+
+// 断开连接之后可以重连的设备
+type Reconnector interface {
+	// 等待设备重新连接，直到成功或者 ctx 结束
+	Reconnect(ctx context.Context) error
+}
+
+// 重启 d 上的应用，d 没有实现 Restarter 时返回错误
+func Restart(d Device, app string) error {
+	r, ok := d.(Restarter)
+	if !ok {
+		return fmt.Errorf("device [%s] can not restart app: %w", d.ID(), ErrUnsupported)
+	}
+	return r.Restart(app)
+}
+
+// 初始化 d，d 没有实现 Initializer 时不需要初始化
+func Init(d Device) error {
+	i, ok := d.(Initializer)
+	if !ok {
+		return nil
+	}
+	return i.Init()
+}
+
+// 使用 adb 控制的 Android 设备，截图使用 tools.InitTools 推送到设备上的 screencap
+type ADBDevice struct {
+	Device adb.Device
+	// Init 时执行，例如 tools.InitTools，为 nil 时不需要初始化
+	Setup func(adb.Device) error
+}
+
+func (d *ADBDevice) ID() string {
+	return d.Device.ID
+}
+
+func (d *ADBDevice) Screenshot() ([]byte, error) {
+	return d.Device.Cmd("shell /data/local/tmp/screencap 50")
+}
+
+func (d *ADBDevice) Tap(x, y, duration int) error {
+	return d.Device.Input.Press(x, y, duration)
+}
+
+func (d *ADBDevice) Swipe(x1, y1, x2, y2, duration int) error {
+	return d.Device.Input.Swipe(x1, y1, x2, y2, duration)
+}
+
+func (d *ADBDevice) Key(key string) error {
+	_, err := d.Device.Cmd("shell input keyevent " + key)
+	return err
+}
+
+func (d *ADBDevice) Shell(cmd string) ([]byte, error) {
+	return d.Device.Cmd("shell " + cmd)
+}
+
+func (d *ADBDevice) ScreenSize() (image.Point, error) {
+	return ScreenSize(d.Device)
+}
+
+// 使用 am 结束应用，然后使用 monkey 启动应用的 launcher activity
+// app 会被转义，不会被设备上的 shell 解释
+func (d *ADBDevice) Restart(app string) error {
+	app = "'" + strings.ReplaceAll(app, "'", `'\''`) + "'"
+	_, err := d.Device.Cmd("shell am force-stop " + app)
+	if err != nil {
+		return err
+	}
+	_, err = d.Device.Cmd("shell monkey -p " + app + " -c android.intent.category.LAUNCHER 1")
+	return err
+}
+
+func (d *ADBDevice) Init() error {
+	if d.Setup == nil {
+		return nil
+	}
+	err := d.Setup(d.Device)
+	if err != nil {
+		return fmt.Errorf("failed to init device [%s]: %w", d.Device.ID, err)
+	}
+	return nil
+}
+
+// 使用 ADB.Reconnect 等待设备重新上线
+func (d *ADBDevice) Reconnect(ctx context.Context) error {
+	a := NewADB(d.Device.ADBPath)
+	return a.Reconnect(ctx, d.Device.ID)
+}
+
+func NewADBDevice(device adb.Device) *ADBDevice {
+	return &ADBDevice{Device: device}
+}
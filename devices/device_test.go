@@ -0,0 +1,70 @@
+package devices_test
+
+import (
+	"errors"
+	"image"
+	"testing"
+
+	"github.com/HumXC/adb-helper"
+	"github.com/HumXC/give-me-time/devices"
+)
+
+func TestADBDevice(t *testing.T) {
+	cmds := make([]string, 0)
+	var d devices.Device = devices.NewADBDevice(adb.Device{ID: "a", Cmd: func(cmd string) ([]byte, error) {
+		cmds = append(cmds, cmd)
+		if cmd == "shell wm size" {
+			return []byte("Physical size: 1080x2400\n"), nil
+		}
+		return nil, nil
+	}})
+	if d.ID() != "a" {
+		t.Errorf("want: a, got: %s", d.ID())
+	}
+	_ = d.Key(devices.KeyBack)
+	_, _ = d.Shell("am force-stop com.example")
+	err := devices.Restart(d, "com.example; reboot")
+	if err != nil {
+		t.Fatal(err)
+	}
+	size, err := d.ScreenSize()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !size.Eq(image.Pt(1080, 2400)) {
+		t.Errorf("want: 1080x2400, got: %v", size)
+	}
+	want := []string{
+		"shell input keyevent KEYCODE_BACK",
+		"shell am force-stop com.example",
+		"shell am force-stop 'com.example; reboot'",
+		"shell monkey -p 'com.example; reboot' -c android.intent.category.LAUNCHER 1",
+		"shell wm size",
+	}
+	if len(cmds) != len(want) {
+		t.Fatalf("want: %v, got: %v", want, cmds)
+	}
+	for i := range want {
+		if cmds[i] != want[i] {
+			t.Errorf("want: %s, got: %s", want[i], cmds[i])
+		}
+	}
+}
+
+func TestADBDeviceInit(t *testing.T) {
+	d := devices.NewADBDevice(adb.Device{ID: "a"})
+	err := devices.Init(d)
+	if err != nil {
+		t.Error(err)
+	}
+	d.Setup = func(adb.Device) error { return errors.New("push failed") }
+	err = devices.Init(d)
+	if err == nil {
+		t.Error("case [setup failed] should be an error, but not")
+	}
+	x := &devices.X11Device{}
+	err = devices.Restart(x, "com.example")
+	if !errors.Is(err, devices.ErrUnsupported) {
+		t.Errorf("want: %v, got: %v", devices.ErrUnsupported, err)
+	}
+}
@@ -0,0 +1,349 @@
+package devices
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"image"
+	_ "image/jpeg"
+	_ "image/png"
+	"io/fs"
+	"path"
+	"sort"
+	"strings"
+	"sync"
+
+	"gopkg.in/yaml.v3"
+)
+
+// FakeEvent 的类型
+const (
+	FakeTap     = "tap"
+	FakeSwipe   = "swipe"
+	FakeKey     = "key"
+	FakeShell   = "shell"
+	FakeRestart = "restart"
+)
+
+// 模拟设备的配置，通常写在一个 yaml 文件中，例如：
+//
+//	id: fake
+//	start: home
+//	screens:
+//	  home: screens/home.png
+//	  shop: screens/shop.png
+//	transitions:
+//	  - from: home
+//	    tap: {x1: 0, y1: 0, x2: 100, y2: 100}
+//	    to: shop
+//	  - from: shop
+//	    key: KEYCODE_BACK
+//	    to: home
+//
+// Screens 中的路径是相对于配置文件的路径
+// Sequence 中的屏幕会在截图时依次使用，用完之后由 Transitions 决定当前的屏幕
+// Rotation 是设备的旋转，参考 Rotator，屏幕的图片是旋转之后的画面
+type FakeConfig struct {
+	ID          string            `yaml:"id"`
+	Start       string            `yaml:"start"`
+	Rotation    int               `yaml:"rotation"`
+	Screens     map[string]string `yaml:"screens"`
+	Sequence    []string          `yaml:"sequence"`
+	Transitions []FakeTransition  `yaml:"transitions"`
+}
+
+// 状态图中的一条边：在 From 屏幕上 Tap 了区域内的点，从区域内开始 Swipe，或者按下 Key 之后，切换到 To 屏幕
+// Tap, Swipe 和 Key 只能设置其一
+type FakeTransition struct {
+	From  string    `yaml:"from"`
+	Tap   *FakeArea `yaml:"tap"`
+	Swipe *FakeArea `yaml:"swipe"`
+	Key   string    `yaml:"key"`
+	To    string    `yaml:"to"`
+}
+
+type FakeArea struct {
+	X1 int `yaml:"x1"`
+	Y1 int `yaml:"y1"`
+	X2 int `yaml:"x2"`
+	Y2 int `yaml:"y2"`
+}
+
+func (a FakeArea) Rect() image.Rectangle {
+	return image.Rect(a.X1, a.Y1, a.X2, a.Y2)
+}
+
+// 模拟设备上发生的一次输入，Screen 是输入时的屏幕
+type FakeEvent struct {
+	Type     string
+	Screen   string
+	P1, P2   image.Point
+	Duration int
+	Key      string
+	Cmd      string
+	App      string
+}
+
+// 模拟的设备，用于在没有真实设备时测试工程和 engine
+// 截图返回当前屏幕的图片，所有的输入都会被记录，输入可以根据状态图切换当前的屏幕
+type FakeDevice struct {
+	config   FakeConfig
+	screens  map[string][]byte
+	mu       sync.Mutex
+	current  string
+	sequence []string
+	events   []FakeEvent
+}
+
+func (f *FakeDevice) ID() string {
+	return f.config.ID
+}
+
+func (f *FakeDevice) Screenshot() ([]byte, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if len(f.sequence) != 0 {
+		f.current = f.sequence[0]
+		f.sequence = f.sequence[1:]
+	}
+	return f.screens[f.current], nil
+}
+
+func (f *FakeDevice) Tap(x, y, duration int) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	p := image.Pt(x, y)
+	f.record(FakeEvent{Type: FakeTap, P1: p, Duration: duration}, func(t FakeTransition) bool {
+		return t.Tap != nil && p.In(t.Tap.Rect())
+	})
+	return nil
+}
+
+func (f *FakeDevice) Swipe(x1, y1, x2, y2, duration int) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	p1 := image.Pt(x1, y1)
+	f.record(FakeEvent{Type: FakeSwipe, P1: p1, P2: image.Pt(x2, y2), Duration: duration}, func(t FakeTransition) bool {
+		return t.Swipe != nil && p1.In(t.Swipe.Rect())
+	})
+	return nil
+}
+
+func (f *FakeDevice) Key(key string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.record(FakeEvent{Type: FakeKey, Key: key}, func(t FakeTransition) bool {
+		return t.Key == key
+	})
+	return nil
+}
+
+// 只记录命令，不会执行
+func (f *FakeDevice) Shell(cmd string) ([]byte, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.record(FakeEvent{Type: FakeShell, Cmd: cmd}, nil)
+	return nil, nil
+}
+
+// 记录重启的应用，并回到 Start 屏幕
+func (f *FakeDevice) Restart(app string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.record(FakeEvent{Type: FakeRestart, App: app}, nil)
+	f.current = f.config.Start
+	return nil
+}
+
+// 自然方向的屏幕大小，与 "wm size" 相同，Rotation 为 0 时就是当前屏幕图片的大小
+func (f *FakeDevice) ScreenSize() (image.Point, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	c, _, err := image.DecodeConfig(bytes.NewReader(f.screens[f.current]))
+	if err != nil {
+		return image.ZP, fmt.Errorf("failed to decode screen [%s]: %w", f.current, err)
+	}
+	return RotateSize(image.Pt(c.Width, c.Height), f.config.Rotation), nil
+}
+
+func (f *FakeDevice) Rotation() (int, error) {
+	return f.config.Rotation, nil
+}
+
+// 当前的屏幕
+func (f *FakeDevice) Screen() string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.current
+}
+
+// 返回到现在为止记录的所有输入
+func (f *FakeDevice) Events() []FakeEvent {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]FakeEvent(nil), f.events...)
+}
+
+// 记录 e，并使用第一个匹配的 Transition 切换屏幕
+func (f *FakeDevice) record(e FakeEvent, match func(FakeTransition) bool) {
+	e.Screen = f.current
+	f.events = append(f.events, e)
+	if match == nil {
+		return
+	}
+	for _, t := range f.config.Transitions {
+		if t.From == f.current && match(t) {
+			f.current = t.To
+			return
+		}
+	}
+}
+
+// 使用 config 和已经读取的屏幕图片创建模拟设备，screens 的 key 是屏幕的名称
+// config.Start 为空时使用 Sequence 的第一个屏幕
+func NewFakeDevice(config FakeConfig, screens map[string][]byte) (*FakeDevice, error) {
+	if config.ID == "" {
+		config.ID = "fake"
+	}
+	if config.Start == "" && len(config.Sequence) != 0 {
+		config.Start = config.Sequence[0]
+	}
+	if config.Screens == nil {
+		config.Screens = make(map[string]string, len(screens))
+		for name := range screens {
+			config.Screens[name] = name
+		}
+	}
+	err := VerifyFake(config)
+	if err != nil {
+		return nil, err
+	}
+	for name := range config.Screens {
+		if _, ok := screens[name]; !ok {
+			return nil, fmt.Errorf("img of screen [%s] is missing", name)
+		}
+	}
+	return &FakeDevice{
+		config:   config,
+		screens:  screens,
+		current:  config.Start,
+		sequence: append([]string(nil), config.Sequence...),
+	}, nil
+}
+
+// 检查模拟设备的配置：
+// - Start 和 Sequence 中的屏幕必须已经定义
+// - Transition 的 From 和 To 必须已经定义，Tap, Swipe 和 Key 只能设置其一
+// - Rotation 只能是 0 到 3
+func VerifyFake(config FakeConfig) error {
+	makeErr := func(err error) error {
+		return fmt.Errorf("failed to verify fake device: %w", err)
+	}
+	has := func(name string) bool {
+		_, ok := config.Screens[name]
+		return ok
+	}
+	if config.Start == "" {
+		return makeErr(errors.New("start is empty"))
+	}
+	if !has(config.Start) {
+		return makeErr(fmt.Errorf("screen [%s] is undefiend", config.Start))
+	}
+	if config.Rotation < 0 || config.Rotation > 3 {
+		return makeErr(fmt.Errorf("rotation [%d] out of range", config.Rotation))
+	}
+	for _, s := range config.Sequence {
+		if !has(s) {
+			return makeErr(fmt.Errorf("screen [%s] is undefiend", s))
+		}
+	}
+	for i, t := range config.Transitions {
+		if !has(t.From) {
+			return makeErr(fmt.Errorf("transitions[%d]: screen [%s] is undefiend", i, t.From))
+		}
+		if !has(t.To) {
+			return makeErr(fmt.Errorf("transitions[%d]: screen [%s] is undefiend", i, t.To))
+		}
+		n := 0
+		if t.Tap != nil {
+			n++
+		}
+		if t.Swipe != nil {
+			n++
+		}
+		if t.Key != "" {
+			n++
+		}
+		if n != 1 {
+			return makeErr(fmt.Errorf("transitions[%d]: only one of tap, swipe and key can be set", i))
+		}
+	}
+	return nil
+}
+
+// 从 fsys 中加载模拟设备，file 可以是：
+// - 一个 yaml 配置文件，参考 FakeConfig
+// - 一个目录，目录中的 png 和 jpg 图片按文件名的顺序作为截图的 Sequence
+func LoadFake(fsys fs.FS, file string) (*FakeDevice, error) {
+	makeErr := func(err error) error {
+		return fmt.Errorf("failed to load fake device: %w", err)
+	}
+	stat, err := fs.Stat(fsys, file)
+	if err != nil {
+		return nil, makeErr(err)
+	}
+	config := FakeConfig{}
+	dir := file
+	if stat.IsDir() {
+		config.Screens, config.Sequence, err = fakeScreens(fsys, dir)
+		if err != nil {
+			return nil, makeErr(err)
+		}
+	} else {
+		dir = path.Dir(file)
+		b, err := fs.ReadFile(fsys, file)
+		if err != nil {
+			return nil, makeErr(err)
+		}
+		err = yaml.Unmarshal(b, &config)
+		if err != nil {
+			return nil, makeErr(err)
+		}
+	}
+	screens := make(map[string][]byte, len(config.Screens))
+	for name, p := range config.Screens {
+		screens[name], err = fs.ReadFile(fsys, path.Join(dir, p))
+		if err != nil {
+			return nil, makeErr(err)
+		}
+	}
+	f, err := NewFakeDevice(config, screens)
+	if err != nil {
+		return nil, makeErr(err)
+	}
+	return f, nil
+}
+
+// 返回 dir 中的所有图片，屏幕的名称是不带扩展名的文件名
+func fakeScreens(fsys fs.FS, dir string) (map[string]string, []string, error) {
+	entries, err := fs.ReadDir(fsys, dir)
+	if err != nil {
+		return nil, nil, err
+	}
+	screens := make(map[string]string)
+	sequence := make([]string, 0, len(entries))
+	for _, e := range entries {
+		ext := strings.ToLower(path.Ext(e.Name()))
+		if e.IsDir() || !(ext == ".png" || ext == ".jpg" || ext == ".jpeg") {
+			continue
+		}
+		name := strings.TrimSuffix(e.Name(), path.Ext(e.Name()))
+		screens[name] = e.Name()
+		sequence = append(sequence, name)
+	}
+	if len(sequence) == 0 {
+		return nil, nil, fmt.Errorf("no img in [%s]", dir)
+	}
+	sort.Strings(sequence)
+	return screens, sequence, nil
+}
@@ -0,0 +1,130 @@
+package devices_test
+
+import (
+	"bytes"
+	"image"
+	"image/png"
+	"testing"
+	"testing/fstest"
+
+	"github.com/HumXC/give-me-time/devices"
+)
+
+func pngOf(t *testing.T, w, h int) []byte {
+	buf := new(bytes.Buffer)
+	err := png.Encode(buf, image.NewRGBA(image.Rect(0, 0, w, h)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func TestFakeDevice(t *testing.T) {
+	fsys := fstest.MapFS{
+		"fake/fake.yaml": {Data: []byte(`
+start: home
+screens:
+  home: screens/home.png
+  shop: screens/shop.png
+transitions:
+  - from: home
+    tap: {x1: 0, y1: 0, x2: 100, y2: 100}
+    to: shop
+  - from: shop
+    key: KEYCODE_BACK
+    to: home
+`)},
+		"fake/screens/home.png": {Data: pngOf(t, 20, 10)},
+		"fake/screens/shop.png": {Data: pngOf(t, 10, 20)},
+	}
+	f, err := devices.LoadFake(fsys, "fake/fake.yaml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var d devices.Device = f
+	size, err := d.ScreenSize()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !size.Eq(image.Pt(20, 10)) {
+		t.Errorf("want: 20x10, got: %v", size)
+	}
+	// 区域外的点不会切换屏幕
+	_ = d.Tap(200, 200, 0)
+	_ = d.Key(devices.KeyBack)
+	if f.Screen() != "home" {
+		t.Errorf("want: home, got: %s", f.Screen())
+	}
+	_ = d.Tap(50, 50, 0)
+	if f.Screen() != "shop" {
+		t.Errorf("want: shop, got: %s", f.Screen())
+	}
+	img, _ := d.Screenshot()
+	if !bytes.Equal(img, fsys["fake/screens/shop.png"].Data) {
+		t.Error("screenshot should be [shop]")
+	}
+	_ = d.Swipe(0, 0, 10, 10, 100)
+	_ = d.Key(devices.KeyBack)
+	if f.Screen() != "home" {
+		t.Errorf("want: home, got: %s", f.Screen())
+	}
+	_ = d.Tap(50, 50, 0)
+	_ = devices.Restart(d, "com.example")
+	if f.Screen() != "home" {
+		t.Errorf("restart want: home, got: %s", f.Screen())
+	}
+	es := f.Events()
+	if len(es) != 7 || es[2].Type != devices.FakeTap || es[2].Screen != "home" || es[3].Type != devices.FakeSwipe {
+		t.Errorf("unexpected events: %+v", es)
+	}
+	if e := es[6]; e.Type != devices.FakeRestart || e.App != "com.example" || e.Screen != "shop" {
+		t.Errorf("unexpected restart event: %+v", e)
+	}
+}
+
+func TestLoadFakeDir(t *testing.T) {
+	fsys := fstest.MapFS{
+		"shots/2.png":    {Data: pngOf(t, 1, 1)},
+		"shots/1.png":    {Data: pngOf(t, 1, 1)},
+		"shots/note.txt": {Data: []byte("not a screen")},
+	}
+	f, err := devices.LoadFake(fsys, "shots")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 按文件名的顺序截图，用完之后停在最后一张
+	for _, want := range []string{"1", "2", "2"} {
+		_, _ = f.Screenshot()
+		if f.Screen() != want {
+			t.Errorf("want: %s, got: %s", want, f.Screen())
+		}
+	}
+}
+
+func TestVerifyFake(t *testing.T) {
+	screens := map[string]string{"a": "a.png", "b": "b.png"}
+	area := &devices.FakeArea{X2: 10, Y2: 10}
+	good := devices.FakeConfig{
+		Start:       "a",
+		Screens:     screens,
+		Sequence:    []string{"a", "b"},
+		Transitions: []devices.FakeTransition{{From: "a", Tap: area, To: "b"}},
+	}
+	if err := devices.VerifyFake(good); err != nil {
+		t.Error(err)
+	}
+	bads := map[string]devices.FakeConfig{
+		"no start":        {Screens: screens},
+		"undefined start": {Start: "c", Screens: screens},
+		"undefined seq":   {Start: "a", Screens: screens, Sequence: []string{"c"}},
+		"undefined to":    {Start: "a", Screens: screens, Transitions: []devices.FakeTransition{{From: "a", Tap: area, To: "c"}}},
+		"no input":        {Start: "a", Screens: screens, Transitions: []devices.FakeTransition{{From: "a", To: "b"}}},
+		"bad rotation":    {Start: "a", Screens: screens, Rotation: 4},
+		"tap and key":     {Start: "a", Screens: screens, Transitions: []devices.FakeTransition{{From: "a", Tap: area, Key: devices.KeyBack, To: "b"}}},
+	}
+	for k, v := range bads {
+		if err := devices.VerifyFake(v); err == nil {
+			t.Error("case [" + k + "] should be an error, but not")
+		}
+	}
+}
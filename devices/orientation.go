@@ -0,0 +1,338 @@
+package devices
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"image/draw"
+	"image/png"
+	"regexp"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/HumXC/give-me-time/engine/project"
+)
+
+// 缓存的设备旋转的有效时间，超过之后在下一次截图时重新获取
+// 截图的大小变化时也会重新获取
+const RotationTTL = 5 * time.Second
+
+// 可以获取屏幕旋转的设备，没有实现这个接口的设备视为没有旋转
+// 旋转是从自然方向开始逆时针旋转 90° 的次数，也就是 0 到 3，与 Android 的 ROTATION_0 到 ROTATION_270 相同
+type Rotator interface {
+	Rotation() (int, error)
+}
+
+// 通过 "dumpsys input" 获取屏幕的旋转，获取不到时使用 "dumpsys window displays"
+func (d *ADBDevice) Rotation() (int, error) {
+	out, err := d.Device.Cmd("shell dumpsys input")
+	if err == nil {
+		r, e := ParseRotation(string(out))
+		if e == nil {
+			return r, nil
+		}
+	}
+	out, err = d.Device.Cmd("shell dumpsys window displays")
+	if err != nil {
+		return 0, fmt.Errorf("adb error: %w", err)
+	}
+	return ParseRotation(string(out))
+}
+
+var (
+	// "dumpsys input" 中 display 0 的 viewport
+	viewportRegexp = regexp.MustCompile(`Viewport [A-Z]+: displayId=0,.*?orientation=(\d+)`)
+	// "dumpsys window displays" 中每个 display 的开头
+	displayRegexp  = regexp.MustCompile(`Display: mDisplayId=(\d+)`)
+	rotationRegexp = regexp.MustCompile(`(?:mCurrentRotation=|mRotation=)(ROTATION_)?(\d+)`)
+)
+
+// 解析 dumpsys 输出中 display 0 的屏幕旋转，支持：
+//
+//	Viewport INTERNAL: displayId=0, uniqueId=local:0, port=0, orientation=1, ...
+//	mCurrentRotation=ROTATION_90
+//	mRotation=1
+//
+// "dumpsys window displays" 中有多个 display 时只使用 "Display: mDisplayId=0" 之后的部分
+func ParseRotation(out string) (int, error) {
+	if m := viewportRegexp.FindStringSubmatch(out); m != nil {
+		return parseRotation(m[0], m[1], false)
+	}
+	m := rotationRegexp.FindStringSubmatch(displaySection(out, "0"))
+	if m == nil {
+		return 0, fmt.Errorf("can not parse rotation")
+	}
+	return parseRotation(m[0], m[2], m[1] != "")
+}
+
+func parseRotation(match, n string, degrees bool) (int, error) {
+	r, _ := strconv.Atoi(n)
+	if degrees {
+		r /= 90
+	}
+	if r < 0 || r > 3 {
+		return 0, fmt.Errorf("rotation [%s] out of range", match)
+	}
+	return r, nil
+}
+
+// 返回 out 中 display id 的部分，out 中没有 display 的开头时返回整个 out
+func displaySection(out, id string) string {
+	locs := displayRegexp.FindAllStringSubmatchIndex(out, -1)
+	if len(locs) == 0 {
+		return out
+	}
+	for i, loc := range locs {
+		if out[loc[2]:loc[3]] != id {
+			continue
+		}
+		end := len(out)
+		if i+1 < len(locs) {
+			end = locs[i+1][0]
+		}
+		return out[loc[0]:end]
+	}
+	return ""
+}
+
+// 屏幕方向固定为 Orientation 的设备，截图，输入和 ScreenSize 都使用游戏的逻辑坐标
+// 截图会被旋转到 Orientation 的方向，Tap 和 Swipe 的坐标会被转换为设备输入的坐标
+// 设备的旋转会被缓存，截图的大小变化或者超过 RotationTTL 时更新
+// Orientation 为空时截图和输入直接使用 Device，只有 ScreenSize 会获取设备的旋转
+type OrientedDevice struct {
+	Device
+	Orientation string
+
+	mu       sync.Mutex
+	natural  image.Point
+	display  int
+	logical  int
+	updated  time.Time
+	lastShot image.Point
+}
+
+func (d *OrientedDevice) Screenshot() ([]byte, error) {
+	if d.Orientation == "" {
+		return d.Device.Screenshot()
+	}
+	data, err := d.Device.Screenshot()
+	if err != nil {
+		return nil, err
+	}
+	c, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode screenshot: %w", err)
+	}
+	size := image.Pt(c.Width, c.Height)
+	d.mu.Lock()
+	changed := !d.lastShot.Eq(image.ZP) && !d.lastShot.Eq(size)
+	d.lastShot = size
+	d.mu.Unlock()
+	if changed {
+		err = d.update()
+		if err != nil {
+			return nil, err
+		}
+	}
+	natural, display, logical, err := d.state()
+	if err != nil {
+		return nil, err
+	}
+	// 截图一般是设备当前方向的画面，大小对不上时是另一个方向的画面，例如没有旋转的原始画面
+	shot := display
+	if RotateSize(natural, shot) != size {
+		shot = 1
+		if display%2 == 1 {
+			shot = 0
+		}
+	}
+	if (logical-shot)%4 == 0 {
+		return data, nil
+	}
+	img, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode screenshot: %w", err)
+	}
+	buf := new(bytes.Buffer)
+	enc := png.Encoder{CompressionLevel: png.BestSpeed}
+	err = enc.Encode(buf, RotateImage(img, logical-shot))
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode screenshot: %w", err)
+	}
+	return buf.Bytes(), nil
+}
+
+func (d *OrientedDevice) Tap(x, y, duration int) error {
+	if d.Orientation == "" {
+		return d.Device.Tap(x, y, duration)
+	}
+	p, err := d.toDisplay(image.Pt(x, y))
+	if err != nil {
+		return err
+	}
+	return d.Device.Tap(p.X, p.Y, duration)
+}
+
+func (d *OrientedDevice) Swipe(x1, y1, x2, y2, duration int) error {
+	if d.Orientation == "" {
+		return d.Device.Swipe(x1, y1, x2, y2, duration)
+	}
+	p1, err := d.toDisplay(image.Pt(x1, y1))
+	if err != nil {
+		return err
+	}
+	p2, err := d.toDisplay(image.Pt(x2, y2))
+	if err != nil {
+		return err
+	}
+	return d.Device.Swipe(p1.X, p1.Y, p2.X, p2.Y, duration)
+}
+
+// 重启应用与屏幕方向无关，直接使用 Device
+func (d *OrientedDevice) Restart(app string) error {
+	return Restart(d.Device, app)
+}
+
+// 游戏逻辑坐标中的屏幕大小
+// Orientation 为空时是设备当前方向的大小，Device.ScreenSize 是自然方向的大小，需要按照设备的旋转转换
+func (d *OrientedDevice) ScreenSize() (image.Point, error) {
+	natural, _, logical, err := d.state()
+	if err != nil {
+		return image.ZP, err
+	}
+	return RotateSize(natural, logical), nil
+}
+
+// 把逻辑坐标中的点转换为设备输入的坐标，输入的坐标是设备当前方向的坐标
+func (d *OrientedDevice) toDisplay(p image.Point) (image.Point, error) {
+	natural, display, logical, err := d.state()
+	if err != nil {
+		return image.ZP, err
+	}
+	return RotatePoint(p, RotateSize(natural, logical), display-logical), nil
+}
+
+// 返回自然方向的屏幕大小，设备当前的旋转和逻辑坐标对应的旋转，还没有获取过或者超过 RotationTTL 时先更新
+func (d *OrientedDevice) state() (natural image.Point, display, logical int, err error) {
+	d.mu.Lock()
+	expired := time.Since(d.updated) > RotationTTL
+	d.mu.Unlock()
+	if expired {
+		err = d.update()
+		if err != nil {
+			return
+		}
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.natural, d.display, d.logical, nil
+}
+
+// 更新设备的旋转，并根据 Orientation 选择逻辑坐标对应的旋转
+func (d *OrientedDevice) update() error {
+	d.mu.Lock()
+	natural := d.natural
+	d.mu.Unlock()
+	if natural.Eq(image.ZP) {
+		size, err := d.Device.ScreenSize()
+		if err != nil {
+			return err
+		}
+		natural = size
+	}
+	display := 0
+	if r, ok := d.Device.(Rotator); ok {
+		var err error
+		display, err = r.Rotation()
+		if err != nil {
+			return err
+		}
+	}
+	logical := display
+	if d.Orientation != "" && Orientation(RotateSize(natural, display)) != d.Orientation {
+		// 设备当前的方向与游戏不同时，横屏使用 ROTATION_90，竖屏使用 ROTATION_0
+		logical = 1
+		if display%2 == 1 {
+			logical = 0
+		}
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.natural = natural
+	d.display = display
+	d.logical = logical
+	d.updated = time.Now()
+	return nil
+}
+
+// orientation 为空时使用设备当前的方向
+func NewOrientedDevice(d Device, orientation string) *OrientedDevice {
+	return &OrientedDevice{Device: d, Orientation: orientation}
+}
+
+// 宽大于高时是横屏，否则是竖屏，返回 project.OrientationPortrait 或者 project.OrientationLandscape
+func Orientation(size image.Point) string {
+	if size.X > size.Y {
+		return project.OrientationLandscape
+	}
+	return project.OrientationPortrait
+}
+
+// 旋转 turns 次之后的屏幕大小
+func RotateSize(size image.Point, turns int) image.Point {
+	if turns%2 != 0 {
+		return image.Pt(size.Y, size.X)
+	}
+	return size
+}
+
+// 把大小为 size 的屏幕上的点 p 逆时针旋转 turns 次，turns 可以是负数
+func RotatePoint(p, size image.Point, turns int) image.Point {
+	for i := 0; i < (turns%4+4)%4; i++ {
+		p = image.Pt(p.Y, size.X-1-p.X)
+		size = image.Pt(size.Y, size.X)
+	}
+	return p
+}
+
+// 把图片逆时针旋转 turns 次，turns 可以是负数，与 RotatePoint 的结果相同
+func RotateImage(img image.Image, turns int) image.Image {
+	turns = (turns%4 + 4) % 4
+	b := img.Bounds()
+	src, ok := img.(*image.RGBA)
+	if !ok || !b.Min.Eq(image.ZP) {
+		src = image.NewRGBA(image.Rect(0, 0, b.Dx(), b.Dy()))
+		draw.Draw(src, src.Bounds(), img, b.Min, draw.Src)
+	}
+	if turns == 0 {
+		return src
+	}
+	w, h := b.Dx(), b.Dy()
+	size := RotateSize(image.Pt(w, h), turns)
+	dst := image.NewRGBA(image.Rect(0, 0, size.X, size.Y))
+	// 源图片中的 (x, y) 在 dst.Pix 中的位置是 origin + x*dx + y*dy
+	ds := dst.Stride
+	var origin, dx, dy int
+	switch turns {
+	case 1:
+		// (x, y) -> (y, w-1-x)
+		origin, dx, dy = (w-1)*ds, -ds, 4
+	case 2:
+		// (x, y) -> (w-1-x, h-1-y)
+		origin, dx, dy = (h-1)*ds+(w-1)*4, -4, -ds
+	case 3:
+		// (x, y) -> (h-1-y, x)
+		origin, dx, dy = (h-1)*4, ds, -4
+	}
+	for y := 0; y < h; y++ {
+		si := y * src.Stride
+		di := origin + y*dy
+		for x := 0; x < w; x++ {
+			copy(dst.Pix[di:di+4], src.Pix[si:si+4])
+			si += 4
+			di += dx
+		}
+	}
+	return dst
+}
@@ -0,0 +1,192 @@
+package devices_test
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/png"
+	"strings"
+	"testing"
+
+	"github.com/HumXC/adb-helper"
+	"github.com/HumXC/give-me-time/devices"
+	"github.com/HumXC/give-me-time/engine/project"
+)
+
+func TestParseRotation(t *testing.T) {
+	goods := map[string]int{
+		"  Viewport INTERNAL: displayId=0, uniqueId=local:0, port=0, orientation=3, logicalFrame=[0, 0, 2400, 1080]\n": 3,
+		"  mCurrentRotation=ROTATION_180\n":   2,
+		"mRotation=0 mAltOrientation=false\n": 0,
+		// 多个 display 时只使用 display 0
+		"  Viewport EXTERNAL: displayId=2, uniqueId=local:1, port=1, orientation=1\n" +
+			"  Viewport INTERNAL: displayId=0, uniqueId=local:0, port=0, orientation=0\n": 0,
+		"Display: mDisplayId=2\n  mCurrentRotation=ROTATION_90\n" +
+			"Display: mDisplayId=0\n  mCurrentRotation=ROTATION_270\n": 3,
+	}
+	for out, want := range goods {
+		got, err := devices.ParseRotation(out)
+		if err != nil {
+			t.Errorf("case [%q]: %v", out, err)
+			continue
+		}
+		if got != want {
+			t.Errorf("case [%q] want: %d, got: %d", out, want, got)
+		}
+	}
+	bads := []string{
+		"",
+		"SurfaceOrientation: 1",
+		"mCurrentRotation=ROTATION_360",
+		"Display: mDisplayId=2\n  mCurrentRotation=ROTATION_90\n",
+	}
+	for _, out := range bads {
+		_, err := devices.ParseRotation(out)
+		if err == nil {
+			t.Errorf("case [%q] should be an error, but not", out)
+		}
+	}
+}
+
+func TestRotatePoint(t *testing.T) {
+	size := image.Pt(1080, 2400)
+	// 逆时针旋转一次，自然方向的右上角变成左上角
+	if p := devices.RotatePoint(image.Pt(1079, 0), size, 1); !p.Eq(image.Pt(0, 0)) {
+		t.Errorf("want: (0,0), got: %v", p)
+	}
+	for turns := -4; turns <= 4; turns++ {
+		p := image.Pt(10, 20)
+		back := devices.RotatePoint(devices.RotatePoint(p, size, turns), devices.RotateSize(size, turns), -turns)
+		if !back.Eq(p) {
+			t.Errorf("turns [%d] want: %v, got: %v", turns, p, back)
+		}
+	}
+}
+
+func TestRotateImage(t *testing.T) {
+	img := image.NewRGBA(image.Rect(0, 0, 2, 3))
+	red := color.RGBA{R: 255, A: 255}
+	img.Set(1, 0, red)
+	got := devices.RotateImage(img, 1)
+	if got.Bounds().Size() != image.Pt(3, 2) || got.At(0, 0) != red {
+		t.Errorf("unexpected image: %v, %v", got.Bounds(), got.At(0, 0))
+	}
+	// 每个像素都与 RotatePoint 的结果相同
+	size := image.Pt(3, 5)
+	img = image.NewRGBA(image.Rect(0, 0, size.X, size.Y))
+	for y := 0; y < size.Y; y++ {
+		for x := 0; x < size.X; x++ {
+			img.Set(x, y, color.RGBA{R: uint8(x), G: uint8(y), A: 255})
+		}
+	}
+	for turns := -1; turns <= 4; turns++ {
+		got := devices.RotateImage(img, turns)
+		if got.Bounds().Size() != devices.RotateSize(size, turns) {
+			t.Fatalf("turns [%d] unexpected size: %v", turns, got.Bounds())
+		}
+		for y := 0; y < size.Y; y++ {
+			for x := 0; x < size.X; x++ {
+				p := devices.RotatePoint(image.Pt(x, y), size, turns)
+				if got.At(p.X, p.Y) != img.At(x, y) {
+					t.Errorf("turns [%d] pixel %v want: %v, got: %v", turns, image.Pt(x, y), img.At(x, y), got.At(p.X, p.Y))
+				}
+			}
+		}
+	}
+}
+
+func TestOrientedDevice(t *testing.T) {
+	// 竖屏的设备横过来运行横屏游戏，截图是没有旋转的原始画面
+	shot := new(bytes.Buffer)
+	_ = png.Encode(shot, image.NewRGBA(image.Rect(0, 0, 1080, 2400)))
+	cmds := make([]string, 0)
+	d := devices.NewADBDevice(adb.Device{ID: "a", Cmd: func(cmd string) ([]byte, error) {
+		cmds = append(cmds, cmd)
+		switch cmd {
+		case "shell wm size":
+			return []byte("Physical size: 1080x2400\n"), nil
+		case "shell dumpsys input":
+			return []byte("  Viewport INTERNAL: displayId=0, uniqueId=local:0, port=0, orientation=1\n"), nil
+		case "shell /data/local/tmp/screencap 50":
+			return shot.Bytes(), nil
+		}
+		return nil, nil
+	}})
+	o := devices.NewOrientedDevice(d, project.OrientationLandscape)
+	size, err := o.ScreenSize()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !size.Eq(image.Pt(2400, 1080)) {
+		t.Errorf("want: 2400x1080, got: %v", size)
+	}
+	b, err := o.Screenshot()
+	if err != nil {
+		t.Fatal(err)
+	}
+	c, err := png.DecodeConfig(bytes.NewReader(b))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c.Width != 2400 || c.Height != 1080 {
+		t.Errorf("want: 2400x1080, got: %dx%d", c.Width, c.Height)
+	}
+	// 截图的大小没有变化时使用缓存的旋转
+	_, _ = o.Screenshot()
+	dumpsys := 0
+	for _, cmd := range cmds {
+		if strings.HasPrefix(cmd, "shell dumpsys") {
+			dumpsys++
+		}
+	}
+	if dumpsys != 1 {
+		t.Errorf("want: 1 dumpsys, got: %v", cmds)
+	}
+	// Orientation 为空时不会获取旋转
+	cmds = cmds[:0]
+	_, _ = devices.NewOrientedDevice(d, "").Screenshot()
+	if len(cmds) != 1 {
+		t.Errorf("want: only screencap, got: %v", cmds)
+	}
+
+	// 设备是竖屏，游戏是横屏时坐标需要转换
+	f, err := devices.NewFakeDevice(devices.FakeConfig{Start: "home"}, map[string][]byte{"home": shot.Bytes()})
+	if err != nil {
+		t.Fatal(err)
+	}
+	o = devices.NewOrientedDevice(f, project.OrientationLandscape)
+	_ = o.Tap(0, 0, 0)
+	_ = o.Swipe(2399, 0, 0, 1079, 100)
+	es := f.Events()
+	if len(es) != 2 || !es[0].P1.Eq(image.Pt(1079, 0)) || !es[1].P1.Eq(image.Pt(1079, 2399)) || !es[1].P2.Eq(image.Pt(0, 0)) {
+		t.Errorf("unexpected events: %+v", es)
+	}
+
+	// 竖屏的设备横过来，Orientation 为空时使用设备当前的方向
+	land := new(bytes.Buffer)
+	_ = png.Encode(land, image.NewRGBA(image.Rect(0, 0, 2400, 1080)))
+	f, err = devices.NewFakeDevice(devices.FakeConfig{Start: "home", Rotation: 1}, map[string][]byte{"home": land.Bytes()})
+	if err != nil {
+		t.Fatal(err)
+	}
+	size, err = f.ScreenSize()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !size.Eq(image.Pt(1080, 2400)) {
+		t.Errorf("natural size want: 1080x2400, got: %v", size)
+	}
+	o = devices.NewOrientedDevice(f, "")
+	size, err = o.ScreenSize()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !size.Eq(image.Pt(2400, 1080)) {
+		t.Errorf("want: 2400x1080, got: %v", size)
+	}
+	_ = o.Tap(2000, 100, 0)
+	es = f.Events()
+	if len(es) != 1 || !es[0].P1.Eq(image.Pt(2000, 100)) {
+		t.Errorf("unexpected events: %+v", es)
+	}
+}
@@ -0,0 +1,219 @@
+package devices
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// 使用 "adb connect" 连接一个无线设备，addr 的格式为 host:port
+// 连接成功后会把 addr 保存到已知设备中
+func (a *ADB) Connect(addr string) error {
+	out, err := a.cmd("connect " + addr)
+	if err != nil {
+		return fmt.Errorf("adb error: %w", err)
+	}
+	err = ParseConnect(string(out))
+	if err != nil {
+		return err
+	}
+	return a.remember(addr)
+}
+
+// 使用 "adb pair" 与一个无线设备配对，addr 和 code 是设备 “无线调试” 中显示的配对地址和配对码
+// 配对使用的端口与连接使用的端口不同，配对成功后还需要 Connect
+func (a *ADB) Pair(addr, code string) error {
+	out, err := a.cmd("pair " + addr + " " + code)
+	if err != nil {
+		return fmt.Errorf("adb error: %w", err)
+	}
+	return ParsePair(string(out))
+}
+
+// 断开一个无线设备，并从已知设备中移除
+func (a *ADB) Disconnect(addr string) error {
+	_, err := a.cmd("disconnect " + addr)
+	if err != nil {
+		return fmt.Errorf("adb error: %w", err)
+	}
+	if a.knownFile == "" {
+		return nil
+	}
+	known, err := LoadKnown(a.knownFile)
+	if err != nil {
+		return err
+	}
+	result := make([]string, 0, len(known))
+	for _, k := range known {
+		if k != addr {
+			result = append(result, k)
+		}
+	}
+	return SaveKnown(a.knownFile, result)
+}
+
+// 连接 spec 选择的已知无线设备，返回连接失败的设备和错误，spec 与 FilterDevices 相同，参考 FilterKnown
+func (a *ADB) ConnectKnown(spec string) map[string]error {
+	errs := make(map[string]error)
+	if a.knownFile == "" {
+		return errs
+	}
+	known, err := LoadKnown(a.knownFile)
+	if err != nil {
+		errs[a.knownFile] = err
+		return errs
+	}
+	for _, addr := range FilterKnown(known, spec) {
+		err := a.Connect(addr)
+		if err != nil {
+			errs[addr] = err
+		}
+	}
+	return errs
+}
+
+// 返回 spec 会选择的已知无线设备：
+//   - 空字符串：没有设备，默认使用的第一个设备不能是自动连接的无线设备
+//   - "all"：所有已知的设备
+//   - 逗号分隔的设备 ID：其中已知的设备，没有已知的设备时为空
+func FilterKnown(known []string, spec string) []string {
+	spec = strings.TrimSpace(spec)
+	if spec == "all" {
+		return known
+	}
+	result := make([]string, 0)
+	if spec == "" {
+		return result
+	}
+	for _, id := range strings.Split(spec, ",") {
+		id = strings.TrimSpace(id)
+		for _, k := range known {
+			if k == id {
+				result = append(result, k)
+				break
+			}
+		}
+	}
+	return result
+}
+
+// 等待设备 id 重新上线，无线设备会先重新连接
+// 使用 "adb -s <id> wait-for-device" 等待，直到设备上线或者 ctx 结束
+func (a *ADB) Reconnect(ctx context.Context, id string) error {
+	if IsWireless(id) {
+		err := a.Connect(id)
+		if err != nil {
+			return err
+		}
+	}
+	path := a.path
+	if path == "" {
+		path = "adb"
+	}
+	out, err := exec.CommandContext(ctx, path, "-s", id, "wait-for-device").CombinedOutput()
+	if ctx.Err() != nil {
+		return fmt.Errorf("failed to wait for [%s]: %w", id, ctx.Err())
+	}
+	if err != nil {
+		return fmt.Errorf("adb error: %w: %s", err, strings.TrimSpace(string(out)))
+	}
+	return nil
+}
+
+// 无线设备的 ID 就是 host:port
+func IsWireless(id string) bool {
+	return strings.Contains(id, ":")
+}
+
+func (a *ADB) remember(addr string) error {
+	if a.knownFile == "" {
+		return nil
+	}
+	known, err := LoadKnown(a.knownFile)
+	if err != nil {
+		return err
+	}
+	for _, k := range known {
+		if k == addr {
+			return nil
+		}
+	}
+	return SaveKnown(a.knownFile, append(known, addr))
+}
+
+// 解析 "adb connect" 的输出，adb 连接失败时返回码也可能是 0
+func ParseConnect(out string) error {
+	out = strings.TrimSpace(out)
+	if strings.HasPrefix(out, "connected to") || strings.HasPrefix(out, "already connected to") {
+		return nil
+	}
+	return fmt.Errorf("failed to connect: [%s]", out)
+}
+
+// 解析 "adb pair" 的输出
+func ParsePair(out string) error {
+	out = strings.TrimSpace(out)
+	if strings.Contains(out, "Successfully paired") {
+		return nil
+	}
+	return fmt.Errorf("failed to pair: [%s]", out)
+}
+
+// 默认保存已知设备的文件，在用户的配置目录中
+func DefaultKnownFile() (string, error) {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "give-me-time", "devices.json"), nil
+}
+
+// 读取已知设备，文件不存在时返回空
+func LoadKnown(file string) ([]string, error) {
+	b, err := os.ReadFile(file)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to load known devices: %w", err)
+	}
+	known := make([]string, 0)
+	err = json.Unmarshal(b, &known)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load known devices: %w", err)
+	}
+	return known, nil
+}
+
+// 保存已知设备，会去重并排序
+func SaveKnown(file string, known []string) error {
+	m := make(map[string]struct{})
+	result := make([]string, 0, len(known))
+	for _, k := range known {
+		if _, ok := m[k]; ok {
+			continue
+		}
+		m[k] = struct{}{}
+		result = append(result, k)
+	}
+	sort.Strings(result)
+	b, err := json.MarshalIndent(result, "", "    ")
+	if err != nil {
+		return fmt.Errorf("failed to save known devices: %w", err)
+	}
+	err = os.MkdirAll(filepath.Dir(file), 0755)
+	if err != nil {
+		return fmt.Errorf("failed to save known devices: %w", err)
+	}
+	err = os.WriteFile(file, b, 0644)
+	if err != nil {
+		return fmt.Errorf("failed to save known devices: %w", err)
+	}
+	return nil
+}
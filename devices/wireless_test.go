@@ -0,0 +1,107 @@
+package devices_test
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/HumXC/give-me-time/devices"
+)
+
+func TestParseConnect(t *testing.T) {
+	goods := []string{"connected to 192.168.1.2:5555\n", "already connected to 192.168.1.2:5555\n"}
+	for _, out := range goods {
+		if err := devices.ParseConnect(out); err != nil {
+			t.Errorf("case [%q]: %v", out, err)
+		}
+	}
+	bads := []string{
+		"failed to connect to '192.168.1.2:5555': Connection refused\n",
+		"cannot connect to 192.168.1.2:5555: No route to host\n",
+		"",
+	}
+	for _, out := range bads {
+		if err := devices.ParseConnect(out); err == nil {
+			t.Errorf("case [%q] should be an error, but not", out)
+		}
+	}
+}
+
+func TestParsePair(t *testing.T) {
+	err := devices.ParsePair("Successfully paired to 192.168.1.2:37123 [guid=adb-xxx]\n")
+	if err != nil {
+		t.Error(err)
+	}
+	err = devices.ParsePair("Failed: Wrong password or connection was dropped.\n")
+	if err == nil {
+		t.Error("case [wrong code] should be an error, but not")
+	}
+}
+
+func TestKnown(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "give-me-time", "devices.json")
+	known, err := devices.LoadKnown(file)
+	if err != nil || len(known) != 0 {
+		t.Fatalf("want: empty, got: %v, %v", known, err)
+	}
+	err = devices.SaveKnown(file, []string{"b:5555", "a:5555", "b:5555"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	known, err = devices.LoadKnown(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Join(known, ",") != "a:5555,b:5555" {
+		t.Errorf("want: [a:5555 b:5555], got: %v", known)
+	}
+}
+
+func TestFilterKnown(t *testing.T) {
+	known := []string{"a:5555", "b:5555"}
+	cases := map[string]string{
+		"":                     "",
+		"all":                  "a:5555,b:5555",
+		"emulator-5554":        "",
+		"emulator-5554,b:5555": "b:5555",
+		" b:5555 , a:5555":     "b:5555,a:5555",
+	}
+	for spec, want := range cases {
+		got := strings.Join(devices.FilterKnown(known, spec), ",")
+		if got != want {
+			t.Errorf("case [%s] want: [%s], got: [%s]", spec, want, got)
+		}
+	}
+}
+
+func TestReconnect(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("sh is required")
+	}
+	script := func(body string) string {
+		file := filepath.Join(t.TempDir(), "adb")
+		err := os.WriteFile(file, []byte("#!/bin/sh\n"+body+"\n"), 0755)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return file
+	}
+	a := devices.NewADB(script(`[ "$1 $2 $3" = "-s emulator-5554 wait-for-device" ]`))
+	err := a.Reconnect(context.Background(), "emulator-5554")
+	if err != nil {
+		t.Error(err)
+	}
+	a = devices.NewADB(script("exec sleep 10"))
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	err = a.Reconnect(ctx, "emulator-5554")
+	if !errors.Is(err, context.DeadlineExceeded) || time.Since(start) > 5*time.Second {
+		t.Errorf("want: %v, got: %v", context.DeadlineExceeded, err)
+	}
+}
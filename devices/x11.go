@@ -0,0 +1,267 @@
+package devices
+
+import (
+	"errors"
+	"fmt"
+	"image"
+	"os"
+	"os/exec"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// 滑动时移动鼠标的次数
+const X11SwipeSteps = 10
+
+// Android 按键对应的 X11 keysym，其他的 key 直接作为 keysym 使用，例如 "space" 或者 "ctrl+s"
+var x11Keys = map[string]string{
+	KeyBack:  "Escape",
+	KeyHome:  "Home",
+	KeyEnter: "Return",
+}
+
+// 窗口 ID，例如 xdotool 输出的 "41943047" 或者 xwininfo 输出的 "0x2800007"
+// 只包含数字的窗口名称也会被当作窗口 ID
+var x11WindowID = regexp.MustCompile(`^([0-9]+|0x[0-9a-fA-F]+)$`)
+
+// X11 设备的配置
+// Display 为空时使用环境变量 DISPLAY，可以是 Xvfb 的 display，例如 ":99"
+// Window 是窗口的 ID 或者名称，为空时使用整个屏幕
+// Region 是窗口中的一个区域，截图和输入的坐标都相对于这个区域，为空时使用整个窗口
+type X11Config struct {
+	Display string
+	Window  string
+	Region  image.Rectangle
+}
+
+// 使用 X11 控制的桌面窗口或者屏幕区域，用于桌面上运行的游戏
+// 截图使用 ImageMagick 的 import，输入使用 xdotool，两者都需要已经安装
+type X11Device struct {
+	Display string
+	// 窗口 ID，为空时是整个屏幕
+	Window string
+	Region image.Rectangle
+	// 执行外部命令，为 nil 时在 Display 上执行
+	Run func(name string, args ...string) ([]byte, error)
+}
+
+func (d *X11Device) ID() string {
+	id := "x11" + d.Display
+	if d.Window != "" {
+		id += "-" + d.Window
+	}
+	return id
+}
+
+func (d *X11Device) Screenshot() ([]byte, error) {
+	window := d.Window
+	if window == "" {
+		window = "root"
+	}
+	args := []string{"-window", window}
+	if !d.Region.Empty() {
+		r := d.Region
+		args = append(args, "-crop", fmt.Sprintf("%dx%d+%d+%d", r.Dx(), r.Dy(), r.Min.X, r.Min.Y), "+repage")
+	}
+	return d.run("import", append(args, "png:-")...)
+}
+
+func (d *X11Device) Tap(x, y, duration int) error {
+	if duration == 0 {
+		duration = 100
+	}
+	args := d.mousemove(x, y)
+	args = append(args, "mousedown", "1", "sleep", seconds(duration), "mouseup", "1")
+	_, err := d.run("xdotool", args...)
+	return err
+}
+
+func (d *X11Device) Swipe(x1, y1, x2, y2, duration int) error {
+	if duration == 0 {
+		duration = 100
+	}
+	args := append(d.mousemove(x1, y1), "mousedown", "1")
+	for i := 1; i <= X11SwipeSteps; i++ {
+		x := x1 + (x2-x1)*i/X11SwipeSteps
+		y := y1 + (y2-y1)*i/X11SwipeSteps
+		args = append(args, "sleep", seconds(duration/X11SwipeSteps))
+		args = append(args, d.mousemove(x, y)...)
+	}
+	args = append(args, "mouseup", "1")
+	_, err := d.run("xdotool", args...)
+	return err
+}
+
+// 按键之前会激活窗口
+func (d *X11Device) Key(key string) error {
+	if k, ok := x11Keys[key]; ok {
+		key = k
+	}
+	args := make([]string, 0, 6)
+	if d.Window != "" {
+		args = append(args, "windowactivate", "--sync", d.Window)
+	}
+	args = append(args, "key", "--clearmodifiers", key)
+	_, err := d.run("xdotool", args...)
+	return err
+}
+
+// X11 设备就是本机，命令可能来自工程，不会被执行
+// X11Device 也没有实现 Restarter，Flow 中的 restart 会返回 ErrUnsupported
+func (d *X11Device) Shell(cmd string) ([]byte, error) {
+	return nil, fmt.Errorf("x11 device can not run shell command: %w", ErrUnsupported)
+}
+
+// 设置了 Region 时是 Region 的大小，否则是窗口或者屏幕的大小
+func (d *X11Device) ScreenSize() (image.Point, error) {
+	if !d.Region.Empty() {
+		return d.Region.Size(), nil
+	}
+	if d.Window == "" {
+		out, err := d.run("xdotool", "getdisplaygeometry")
+		if err != nil {
+			return image.ZP, err
+		}
+		var p image.Point
+		_, err = fmt.Sscanf(strings.TrimSpace(string(out)), "%d %d", &p.X, &p.Y)
+		if err != nil {
+			return image.ZP, fmt.Errorf("can not parse display geometry from [%s]", strings.TrimSpace(string(out)))
+		}
+		return p, nil
+	}
+	out, err := d.run("xdotool", "getwindowgeometry", "--shell", d.Window)
+	if err != nil {
+		return image.ZP, err
+	}
+	return ParseWindowGeometry(string(out))
+}
+
+// 移动鼠标到 Region 中的 (x, y)，设置了 Window 时坐标相对于窗口
+func (d *X11Device) mousemove(x, y int) []string {
+	x += d.Region.Min.X
+	y += d.Region.Min.Y
+	if d.Window == "" {
+		return []string{"mousemove", strconv.Itoa(x), strconv.Itoa(y)}
+	}
+	return []string{"mousemove", "--window", d.Window, strconv.Itoa(x), strconv.Itoa(y)}
+}
+
+func (d *X11Device) run(name string, args ...string) ([]byte, error) {
+	if d.Run != nil {
+		return d.Run(name, args...)
+	}
+	cmd := exec.Command(name, args...)
+	cmd.Env = os.Environ()
+	if d.Display != "" {
+		cmd.Env = append(cmd.Env, "DISPLAY="+d.Display)
+	}
+	out, err := cmd.Output()
+	if err != nil {
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) {
+			return nil, fmt.Errorf("%s error: %w: %s", name, err, strings.TrimSpace(string(exitErr.Stderr)))
+		}
+		return nil, fmt.Errorf("%s error: %w", name, err)
+	}
+	return out, nil
+}
+
+func seconds(ms int) string {
+	return strconv.FormatFloat(float64(ms)/1000, 'f', 3, 64)
+}
+
+// 创建 X11 设备，c.Window 是窗口名称时使用 "xdotool search --name" 找到的第一个窗口
+func NewX11Device(c X11Config) (*X11Device, error) {
+	d := &X11Device{Display: c.Display, Region: c.Region}
+	if c.Window == "" || x11WindowID.MatchString(c.Window) {
+		d.Window = c.Window
+		return d, nil
+	}
+	out, err := d.run("xdotool", "search", "--name", c.Window)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find window [%s]: %w", c.Window, err)
+	}
+	ids := strings.Fields(string(out))
+	if len(ids) == 0 {
+		return nil, fmt.Errorf("window [%s] not found", c.Window)
+	}
+	d.Window = ids[0]
+	return d, nil
+}
+
+// 解析 X11 设备的描述，格式为逗号分隔的 key=value，所有的 key 都可以省略，例如：
+//
+//	display=:99,window=My Game,geometry=1280x720+0+40
+//
+// geometry 是 X11 的 WxH+X+Y 格式，对应 X11Config.Region
+func ParseX11(spec string) (X11Config, error) {
+	c := X11Config{}
+	for _, kv := range strings.Split(spec, ",") {
+		kv = strings.TrimSpace(kv)
+		if kv == "" {
+			continue
+		}
+		k, v, ok := strings.Cut(kv, "=")
+		if !ok {
+			return c, fmt.Errorf("invalid x11 spec [%s]", kv)
+		}
+		switch strings.TrimSpace(k) {
+		case "display":
+			c.Display = strings.TrimSpace(v)
+		case "window":
+			c.Window = strings.TrimSpace(v)
+		case "geometry":
+			r, err := ParseGeometry(strings.TrimSpace(v))
+			if err != nil {
+				return c, err
+			}
+			c.Region = r
+		default:
+			return c, fmt.Errorf("x11 spec key [%s] undefiend", k)
+		}
+	}
+	return c, nil
+}
+
+// 解析 X11 的 geometry，例如 "1280x720+0+40"，省略偏移时为 0
+func ParseGeometry(s string) (image.Rectangle, error) {
+	var w, h, x, y int
+	n, _ := fmt.Sscanf(s, "%dx%d+%d+%d", &w, &h, &x, &y)
+	if n != 2 && n != 4 || w <= 0 || h <= 0 {
+		return image.Rectangle{}, fmt.Errorf("can not parse geometry from [%s]", s)
+	}
+	return image.Rect(x, y, x+w, y+h), nil
+}
+
+// 解析 "xdotool getwindowgeometry --shell" 的输出，例如：
+//
+//	WINDOW=41943047
+//	X=0
+//	Y=40
+//	WIDTH=1280
+//	HEIGHT=720
+//	SCREEN=0
+func ParseWindowGeometry(out string) (image.Point, error) {
+	var p image.Point
+	for _, line := range strings.Split(out, "\n") {
+		k, v, ok := strings.Cut(strings.TrimSpace(line), "=")
+		if !ok {
+			continue
+		}
+		n, err := strconv.Atoi(v)
+		if err != nil {
+			continue
+		}
+		switch k {
+		case "WIDTH":
+			p.X = n
+		case "HEIGHT":
+			p.Y = n
+		}
+	}
+	if p.X == 0 || p.Y == 0 {
+		return image.ZP, fmt.Errorf("can not parse window geometry from [%s]", strings.TrimSpace(out))
+	}
+	return p, nil
+}
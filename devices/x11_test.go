@@ -0,0 +1,97 @@
+package devices_test
+
+import (
+	"errors"
+	"image"
+	"strings"
+	"testing"
+
+	"github.com/HumXC/give-me-time/devices"
+)
+
+func TestParseX11(t *testing.T) {
+	goods := map[string]devices.X11Config{
+		"":                           {},
+		"display=:99":                {Display: ":99"},
+		"window=My Game, display=:0": {Display: ":0", Window: "My Game"},
+		"geometry=1280x720+0+40":     {Region: image.Rect(0, 40, 1280, 760)},
+		"geometry=800x600":           {Region: image.Rect(0, 0, 800, 600)},
+	}
+	for spec, want := range goods {
+		got, err := devices.ParseX11(spec)
+		if err != nil {
+			t.Errorf("case [%s]: %v", spec, err)
+			continue
+		}
+		if got != want {
+			t.Errorf("case [%s] want: %+v, got: %+v", spec, want, got)
+		}
+	}
+	bads := []string{"display", "size=1x1", "geometry=1280", "geometry=0x720", "geometry=1x1+1"}
+	for _, spec := range bads {
+		_, err := devices.ParseX11(spec)
+		if err == nil {
+			t.Error("case [" + spec + "] should be an error, but not")
+		}
+	}
+}
+
+func TestParseWindowGeometry(t *testing.T) {
+	got, err := devices.ParseWindowGeometry("WINDOW=41943047\nX=0\nY=40\nWIDTH=1280\nHEIGHT=720\nSCREEN=0\n")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !got.Eq(image.Pt(1280, 720)) {
+		t.Errorf("want: 1280x720, got: %v", got)
+	}
+	_, err = devices.ParseWindowGeometry("X Error of failed request")
+	if err == nil {
+		t.Error("case [X Error] should be an error, but not")
+	}
+}
+
+func TestX11Device(t *testing.T) {
+	d, err := devices.NewX11Device(devices.X11Config{Window: "0x2800007", Region: image.Rect(10, 20, 110, 220)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	cmds := make([]string, 0)
+	d.Run = func(name string, args ...string) ([]byte, error) {
+		cmds = append(cmds, name+" "+strings.Join(args, " "))
+		return nil, nil
+	}
+	var device devices.Device = d
+	_ = device.Tap(1, 2, 0)
+	_ = device.Key(devices.KeyBack)
+	_, _ = device.Screenshot()
+	// 不会在本机上执行命令
+	_, err = device.Shell("touch /tmp/x")
+	if !errors.Is(err, devices.ErrUnsupported) {
+		t.Errorf("want: %v, got: %v", devices.ErrUnsupported, err)
+	}
+	size, err := device.ScreenSize()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !size.Eq(image.Pt(100, 200)) {
+		t.Errorf("want: 100x200, got: %v", size)
+	}
+	want := []string{
+		"xdotool mousemove --window 0x2800007 11 22 mousedown 1 sleep 0.100 mouseup 1",
+		"xdotool windowactivate --sync 0x2800007 key --clearmodifiers Escape",
+		"import -window 0x2800007 -crop 100x200+10+20 +repage png:-",
+	}
+	if len(cmds) != len(want) {
+		t.Fatalf("want: %v, got: %v", want, cmds)
+	}
+	for i := range want {
+		if cmds[i] != want[i] {
+			t.Errorf("want: %s, got: %s", want[i], cmds[i])
+		}
+	}
+	cmds = cmds[:0]
+	_ = device.Swipe(0, 0, 100, 0, 1000)
+	if len(cmds) != 1 || !strings.HasSuffix(cmds[0], "sleep 0.100 mousemove --window 0x2800007 110 20 mouseup 1") {
+		t.Errorf("unexpected swipe: %v", cmds)
+	}
+}
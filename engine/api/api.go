@@ -6,8 +6,8 @@ import (
 	"image"
 	"sync"
 
-	"github.com/HumXC/adb-helper"
 	"github.com/HumXC/give-me-time/cv"
+	"github.com/HumXC/give-me-time/devices"
 	"github.com/otiai10/gosseract/v2"
 	"gocv.io/x/gocv"
 )
@@ -18,9 +18,24 @@ func NewArgsErr(want, got any) error {
 	return fmt.Errorf("%w: want [%v], got [%v]", ErrArgs, want, got)
 }
 
+// 设备的截图或者输入失败，通常是因为设备断开了连接
+type AdbError struct {
+	Err error
+}
+
+func (e *AdbError) Error() string {
+	return "adb error: " + e.Err.Error()
+}
+
+func (e *AdbError) Unwrap() error {
+	return e.Err
+}
+
 type ImgHandler interface {
 	// 模版匹配
-	Find(img gocv.Mat, tmpl gocv.Mat) (float32, image.Point, error)
+	Find(img gocv.Mat, tmpl gocv.Mat, opt cv.MatchOption) (float32, image.Point, error)
+	// 特征点匹配，返回 tmpl 上的 points 投影到 img 上的位置
+	FindFeature(img gocv.Mat, tmpl gocv.Mat, feature cv.Feature, points []image.Point) (float32, []image.Point, error)
 	// Sseract 识别文字
 	Ocr(img []byte) (string, error)
 }
@@ -29,8 +44,16 @@ type imgHanderImpl struct {
 	SseractMu *sync.Mutex
 }
 
-func (i *imgHanderImpl) Find(img gocv.Mat, tmpl gocv.Mat) (float32, image.Point, error) {
-	v, p, err := cv.Find(img, tmpl)
+func (i *imgHanderImpl) Find(img gocv.Mat, tmpl gocv.Mat, opt cv.MatchOption) (float32, image.Point, error) {
+	v, p, err := cv.FindWithOption(img, tmpl, opt)
+	if err != nil {
+		err = fmt.Errorf("cv error: %w", err)
+	}
+	return v, p, err
+}
+
+func (i *imgHanderImpl) FindFeature(img gocv.Mat, tmpl gocv.Mat, feature cv.Feature, points []image.Point) (float32, []image.Point, error) {
+	v, p, err := cv.FindFeaturePoints(img, tmpl, feature, points)
 	if err != nil {
 		err = fmt.Errorf("cv error: %w", err)
 	}
@@ -63,13 +86,13 @@ type ScreencapTool interface {
 	ToByte() ([]byte, error)
 }
 type screencapToolImpl struct {
-	adbCmd adb.ADBRunner
+	device devices.Device
 }
 
 func (s *screencapToolImpl) ToByte() ([]byte, error) {
-	data, err := s.adbCmd("shell /data/local/tmp/screencap 50")
+	data, err := s.device.Screenshot()
 	if err != nil {
-		return nil, fmt.Errorf("adb error: %w", err)
+		return nil, &AdbError{err}
 	}
 	return data, err
 }
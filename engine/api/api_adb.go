@@ -1,33 +1,46 @@
 package api
 
 import (
-	"fmt"
+	"errors"
 	"image"
 
-	"github.com/HumXC/adb-helper"
+	"github.com/HumXC/give-me-time/devices"
 )
 
 type ApiAdb interface {
 	InputHandler
-	// 执行 adb 命令
-	Cmd(string) ([]byte, error)
+	// 按下一个按键，例如 devices.KeyBack
+	Key(key string) error
+	// 结束并重新启动应用 app，设备没有实现 devices.Restarter 时返回错误
+	Restart(app string) error
 }
 
 type apiAdbImpl struct {
-	input adb.Input
-	cmd   adb.ADBRunner
+	device devices.Device
 }
 
-func (a *apiAdbImpl) Cmd(cmd string) ([]byte, error) {
-	out, err := a.cmd(cmd)
+func (a *apiAdbImpl) Key(key string) error {
+	err := a.device.Key(key)
 	if err != nil {
-		return nil, fmt.Errorf("adb error: %w", err)
+		return &AdbError{err}
 	}
-	return out, nil
+	return nil
+}
+
+func (a *apiAdbImpl) Restart(app string) error {
+	err := devices.Restart(a.device, app)
+	if err != nil && !errors.Is(err, devices.ErrUnsupported) {
+		return &AdbError{err}
+	}
+	return err
 }
 
 func (a *apiAdbImpl) Press(x, y, duration int) error {
-	return a.input.Press(x, y, duration)
+	err := a.device.Tap(x, y, duration)
+	if err != nil {
+		return &AdbError{err}
+	}
+	return nil
 }
 
 type SwipeHandler struct {
@@ -45,21 +58,18 @@ func (h *SwipeHandler) Action(duration int) (image.Point, image.Point, bool, err
 
 	err := h.swipe(h.p1.X, h.p1.Y, h.p2.X, h.p2.Y, duration)
 	if err != nil {
-		return image.ZP, image.ZP, false, err
+		return image.ZP, image.ZP, false, &AdbError{err}
 	}
 	return h.p1, h.p2, true, nil
 }
 
 func (a *apiAdbImpl) Swipe(x, y int) InputHandlerSwipeTo {
 	return &SwipeHandler{
-		swipe: a.input.Swipe,
+		swipe: a.device.Swipe,
 		p1:    image.Point{X: x, Y: y},
 	}
 }
 
-func NewApiAdb(device adb.Device) ApiAdb {
-	return &apiAdbImpl{
-		input: device.Input,
-		cmd:   device.Cmd,
-	}
+func NewApiAdb(device devices.Device) ApiAdb {
+	return &apiAdbImpl{device: device}
 }
@@ -5,58 +5,335 @@ import (
 	"errors"
 	"fmt"
 	"image"
+	"image/color"
 	"image/jpeg"
+	_ "image/png"
+	"strings"
+	"time"
 
-	"github.com/HumXC/adb-helper"
+	"github.com/HumXC/give-me-time/cv"
+	"github.com/HumXC/give-me-time/devices"
 	"github.com/HumXC/give-me-time/engine/project"
 	"gocv.io/x/gocv"
 )
 
 // “Element” 类型参数是指在 lua 中以 “click(E.main.start)” 的形式调用
 type ApiImg interface {
-	// 查找元素
+	// 查找元素，元素有多个变体时返回达到各自阈值的变体中得分最高的那个，
+	// 都没有达到阈值时返回得分最高的那个
 	FindE(e string) (image.Point, float32, error)
+	// 与 FindE 相同，第三个返回值是匹配到的变体的名称
+	FindVariantE(e string) (image.Point, float32, string, error)
 	// 返回范围内的文字识别结果
 	Ocr(x1, y1, x2, y2 int) (string, error)
 	OcrE(e string) (string, error)
+	// 返回屏幕上一个点的颜色
+	PixelAt(x, y int) (color.RGBA, error)
+	// 判断元素所在位置的颜色是否符合，e 可以是 point 元素或者 color 元素。
+	// rgb 的格式为 "#RRGGBB"，为空时使用 color 元素中定义的颜色；
+	// tolerance 是每个颜色通道允许的误差，小于 0 时使用 color 元素中定义的误差
+	ColorMatchE(e string, rgb string, tolerance int) (bool, error)
+	// 返回当前屏幕对应的 Scene 的名称，所有 Scene 都使用同一张截图进行判断。
+	// 同时符合多个 Scene 时，返回 Require 最多的那个，都不符合时返回空字符串
+	CurrentScene() (string, error)
+	// 判断一个条件是否满足，img 元素会使用 Threshold 判断是否找到
+	Check(c project.Condition) (bool, error)
+	// 返回元素在屏幕上的位置：img 元素为找到的位置加上 Offset，area 元素为区域的中心，
+	// point 元素为这个点。元素的坐标相对于父元素时，会先确定父元素的位置。
+	// img 元素没有找到时返回的错误是 ErrNotFound
+	PointE(e string) (image.Point, error)
+	// 返回 area 元素在屏幕上的区域
+	AreaE(e string) (image.Rectangle, error)
 	// 锁定与解锁当前 Find 函数的对象
 	Lock() error
 	Unlock() error
 }
+
+// img 元素没有设置 Threshold 时，判断是否找到元素使用的阈值
+const DefaultThreshold = 0.8
+
+var ErrNotFound = errors.New("element not found")
+
+// Frame 是一帧已经解码的屏幕截图，image.Image 和 gocv.Mat 两种形式
+// 都只会在第一次被使用时解码，之后重复使用同一份结果。
+// 使用完毕后需要调用 Close 释放 gocv.Mat
+type Frame struct {
+	data  []byte
+	img   image.Image
+	mat   *gocv.Mat
+	found map[string]match // 在这一帧中已经找到的 img 元素
+}
+
+func NewFrame(data []byte) *Frame {
+	return &Frame{data: data, found: make(map[string]match)}
+}
+
+// 截图的原始数据
+func (f *Frame) Bytes() []byte {
+	return f.data
+}
+
+func (f *Frame) Image() (image.Image, error) {
+	if f.img != nil {
+		return f.img, nil
+	}
+	img, _, err := image.Decode(bytes.NewReader(f.data))
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode frame: %w", err)
+	}
+	f.img = img
+	return img, nil
+}
+
+func (f *Frame) Mat() (gocv.Mat, error) {
+	if f.mat != nil {
+		return *f.mat, nil
+	}
+	mat, err := gocv.IMDecode(f.data, gocv.IMReadUnchanged)
+	if err != nil {
+		return mat, fmt.Errorf("failed to decode frame: %w", err)
+	}
+	f.mat = &mat
+	return mat, nil
+}
+
+func (f *Frame) Close() error {
+	f.img = nil
+	if f.mat == nil {
+		return nil
+	}
+	err := f.mat.Close()
+	f.mat = nil
+	return err
+}
+
 type apiImgImpl struct {
-	imgHander   ImgHandler
-	screencap   ScreencapTool
-	nowImg      []byte
-	elementMat  map[string]gocv.Mat
-	elementArea map[string]project.ElArea
+	imgHander    ImgHandler
+	screencap    ScreencapTool
+	frame        *Frame
+	elementMat   map[string][]gocv.Mat // 与 ElImg.Variants 一一对应
+	elementImg   map[string]project.ElImg
+	elementArea  map[string]project.ElArea
+	elementPoint map[string]project.ElPoint
+	elementColor map[string]project.ElColor
+	scenes       []project.Scene
+	// 最后一次找到的 img 元素的位置，用于在没有锁定时确定子元素的位置
+	lastFound map[string]lastMatch
+}
+
+// 没有锁定时，最后一次找到的位置在这段时间内可以被子元素重复使用
+const LastFoundTTL = time.Second
+
+type lastMatch struct {
+	match
+	at time.Time
 }
 
 func (a *apiImgImpl) FindE(e string) (image.Point, float32, error) {
-	imgB, err := a.GetScreen()
+	m, v, err := a.findE(e)
+	return m.P, v, err
+}
+
+func (a *apiImgImpl) FindVariantE(e string) (image.Point, float32, string, error) {
+	m, v, err := a.findE(e)
 	if err != nil {
-		return image.ZP, 0, fmt.Errorf("can not find element [%s]: %w", e, err)
+		return m.P, v, "", err
+	}
+	return m.P, v, a.elementImg[e].Variants[m.Variant].Name, nil
+}
+
+// 一个 img 元素的匹配结果，Variant 是 ElImg.Variants 的下标
+// P 是模板左上角在屏幕上的位置，Point 是变体的 Offset 在屏幕上的位置
+// 特征点匹配时两者都是通过单应性矩阵投影的，目标被旋转或者缩放时 Point 不等于 P 加上 Offset
+type match struct {
+	P       image.Point
+	Point   image.Point
+	Variant int
+}
+
+// 使用元素的所有变体进行匹配，返回达到自身阈值的变体中得分最高的结果
+// 没有变体达到阈值时返回得分最高的结果，出错的变体会被跳过，所有变体都出错时才返回错误
+func (a *apiImgImpl) findE(e string) (match, float32, error) {
+	tmpls, ok := a.elementMat[e]
+	if !ok {
+		return match{}, -1, fmt.Errorf("img element [%s] undefiend", e)
 	}
-	img, err := gocv.IMDecode(imgB, gocv.IMReadUnchanged)
+	frame, err := a.GetScreen()
 	if err != nil {
-		return image.ZP, 0, fmt.Errorf("can not find element [%s]: %w", e, err)
+		return match{}, 0, fmt.Errorf("can not find element [%s]: %w", e, err)
 	}
-	tmpl, ok := a.elementMat[e]
-	if !ok {
-		return image.ZP, -1, fmt.Errorf("img element [%s] undefiend", e)
+	defer a.release(frame)
+	img, err := frame.Mat()
+	if err != nil {
+		return match{}, 0, fmt.Errorf("can not find element [%s]: %w", e, err)
 	}
-	v, p, err := a.imgHander.Find(img, tmpl)
+	el := a.elementImg[e]
+	best, found := match{}, match{}
+	var bestV, foundV float32
+	matched, passed := false, false
+	var lastErr error
+	for i, tmpl := range tmpls {
+		var v float32
+		m := match{Variant: i}
+		offset := el.Variants[i].Offset
+		switch el.Method {
+		case project.MethodORB:
+			v, m.P, m.Point, err = a.findFeature(img, tmpl, cv.FeatureORB, offset)
+		case project.MethodAKAZE:
+			v, m.P, m.Point, err = a.findFeature(img, tmpl, cv.FeatureAKAZE, offset)
+		default:
+			v, m.P, err = a.imgHander.Find(img, tmpl, matchOption(el.Match))
+			m.Point = m.P.Add(offset)
+		}
+		if err != nil {
+			lastErr = fmt.Errorf("variant [%s]: %w", el.Variants[i].Name, err)
+			continue
+		}
+		if !matched || v > bestV {
+			best, bestV, matched = m, v, true
+		}
+		if v >= threshold(el.Variants[i]) && (!passed || v > foundV) {
+			found, foundV, passed = m, v, true
+		}
+	}
+	if !matched {
+		return match{}, 0, fmt.Errorf("can not find element [%s]: %w", e, lastErr)
+	}
+	// 没有找到时清除最后一次的位置，避免子元素使用已经离开屏幕的父元素
+	if !passed {
+		delete(frame.found, e)
+		delete(a.lastFound, e)
+		return best, bestV, nil
+	}
+	frame.found[e] = found
+	a.lastFound[e] = lastMatch{match: found, at: time.Now()}
+	return found, foundV, nil
+}
+
+func threshold(v project.ElImgVariant) float32 {
+	if v.Threshold == 0 {
+		return DefaultThreshold
+	}
+	return v.Threshold
+}
+
+// 返回 img 元素找到的位置和变体
+// 锁定时同一帧中只会匹配一次；没有锁定时，如果 reuse 为 true 则优先使用 LastFoundTTL 内最后一次找到的位置
+func (a *apiImgImpl) locateImg(e string, reuse bool) (match, error) {
+	if a.frame != nil {
+		if m, ok := a.frame.found[e]; ok {
+			return m, nil
+		}
+	} else if m, ok := a.lastFound[e]; ok && reuse {
+		if time.Since(m.at) <= LastFoundTTL {
+			return m.match, nil
+		}
+		delete(a.lastFound, e)
+	}
+	m, v, err := a.findE(e)
 	if err != nil {
-		return image.ZP, 0, fmt.Errorf("can not find element [%s]: %w", e, err)
+		return match{}, err
+	}
+	if t := threshold(a.elementImg[e].Variants[m.Variant]); v < t {
+		return match{}, fmt.Errorf("%w: [%s] %f < %f", ErrNotFound, e, v, t)
 	}
-	return p, v, nil
+	return m, nil
 }
 
-func (a *apiImgImpl) Ocr(x1, y1, x2, y2 int) (string, error) {
-	img, err := a.screencap.ToByte()
+// 子元素的坐标相对于父元素时，父元素在屏幕上的原点：
+// img 元素为找到的位置（不加上 Offset），area 元素为左上角，point 元素为这个点
+func (a *apiImgImpl) origin(parent string) (image.Point, error) {
+	if parent == "" {
+		return image.ZP, nil
+	}
+	if _, ok := a.elementImg[parent]; ok {
+		m, err := a.locateImg(parent, true)
+		return m.P, err
+	}
+	if _, ok := a.elementArea[parent]; ok {
+		r, err := a.AreaE(parent)
+		return r.Min, err
+	}
+	if _, ok := a.elementPoint[parent]; ok {
+		return a.PointE(parent)
+	}
+	return image.ZP, fmt.Errorf("img, area or point element [%s] undefiend", parent)
+}
+
+func (a *apiImgImpl) PointE(e string) (image.Point, error) {
+	if _, ok := a.elementImg[e]; ok {
+		m, err := a.locateImg(e, false)
+		if err != nil {
+			return image.ZP, err
+		}
+		return m.Point, nil
+	}
+	if _, ok := a.elementArea[e]; ok {
+		r, err := a.AreaE(e)
+		return r.Min.Add(r.Max).Div(2), err
+	}
+	if el, ok := a.elementPoint[e]; ok {
+		o, err := a.origin(el.Parent)
+		if err != nil {
+			return image.ZP, fmt.Errorf("can not locate element [%s]: %w", e, err)
+		}
+		return el.Point.Add(o), nil
+	}
+	return image.ZP, fmt.Errorf("img, area or point element [%s] undefiend", e)
+}
+
+func (a *apiImgImpl) AreaE(e string) (image.Rectangle, error) {
+	el, ok := a.elementArea[e]
+	if !ok {
+		return image.Rectangle{}, fmt.Errorf("area element [%s] undefiend", e)
+	}
+	o, err := a.origin(el.Parent)
 	if err != nil {
-		return "", fmt.Errorf("can not ocr [%d, %d - %d, %d]: %w", x1, y1, x2, y2, err)
+		return image.Rectangle{}, fmt.Errorf("can not locate element [%s]: %w", e, err)
+	}
+	return image.Rectangle{Min: el.P1, Max: el.P2}.Add(o), nil
+}
+
+// 将元素中的模板匹配选项转换成 cv.MatchOption
+func matchOption(m project.Match) cv.MatchOption {
+	opt := cv.DefaultMatchOption
+	switch m.Method {
+	case project.MatchSqdiffNormed:
+		opt.Method = gocv.TmSqdiffNormed
+	case project.MatchCcorrNormed:
+		opt.Method = gocv.TmCcorrNormed
+	case project.MatchCcoeffNormed:
+		opt.Method = gocv.TmCcoeffNormed
+	}
+	switch m.Color {
+	case project.ColorModeColor:
+		opt.Color = cv.ColorModeColor
+	case project.ColorModeGray:
+		opt.Color = cv.ColorModeGray
+	case project.ColorModeHSVH:
+		opt.Color = cv.ColorModeHSVH
+	case project.ColorModeHSVS:
+		opt.Color = cv.ColorModeHSVS
+	case project.ColorModeHSVV:
+		opt.Color = cv.ColorModeHSVV
+	case project.ColorModeCanny:
+		opt.Color = cv.ColorModeCanny
+	}
+	opt.Blur = m.Blur
+	return opt
+}
+
+// 将 tmpl 的左上角和 offset 投影到屏幕上，返回值与模版匹配的左上角和元素的点对应
+func (a *apiImgImpl) findFeature(img, tmpl gocv.Mat, feature cv.Feature, offset image.Point) (float32, image.Point, image.Point, error) {
+	v, ps, err := a.imgHander.FindFeature(img, tmpl, feature, []image.Point{image.ZP, offset})
+	if err != nil || len(ps) != 2 {
+		return v, image.ZP, image.ZP, err
 	}
-	str, err := a.imgHander.Ocr(img)
+	return v, ps[0], ps[1], nil
+}
+
+func (a *apiImgImpl) Ocr(x1, y1, x2, y2 int) (string, error) {
+	str, err := a.ocr(image.Rect(x1, y1, x2, y2))
 	if err != nil {
 		return "", fmt.Errorf("can not ocr [%d, %d - %d, %d]: %w", x1, y1, x2, y2, err)
 	}
@@ -64,72 +341,268 @@ func (a *apiImgImpl) Ocr(x1, y1, x2, y2 int) (string, error) {
 }
 
 func (a *apiImgImpl) OcrE(e string) (string, error) {
-	area, ok := a.elementArea[e]
-	if !ok {
-		return "", fmt.Errorf("area element [%s] undefiend", e)
+	r, err := a.AreaE(e)
+	if err != nil {
+		return "", err
 	}
-	img, err := a.GetScreen()
+	str, err := a.ocr(r)
 	if err != nil {
 		return "", fmt.Errorf("can not ocr element [%s]: %w", e, err)
 	}
-	r := image.Rect(area.P1.X, area.P1.Y, area.P2.X, area.P2.Y)
-	buf := bytes.NewBuffer(img)
-	im, _, err := image.Decode(buf)
+	return str, nil
+}
+
+func (a *apiImgImpl) PixelAt(x, y int) (color.RGBA, error) {
+	frame, err := a.GetScreen()
 	if err != nil {
-		return "", fmt.Errorf("can not ocr element [%s]: %w", e, err)
+		return color.RGBA{}, fmt.Errorf("can not get pixel [%d, %d]: %w", x, y, err)
+	}
+	defer a.release(frame)
+	im, err := frame.Image()
+	if err != nil {
+		return color.RGBA{}, fmt.Errorf("can not get pixel [%d, %d]: %w", x, y, err)
+	}
+	if !image.Pt(x, y).In(im.Bounds()) {
+		return color.RGBA{}, fmt.Errorf("can not get pixel [%d, %d]: %w", x, y, NewArgsErr(im.Bounds(), image.Pt(x, y)))
+	}
+	return color.RGBAModel.Convert(im.At(x, y)).(color.RGBA), nil
+}
+
+func (a *apiImgImpl) ColorMatchE(e string, rgb string, tolerance int) (bool, error) {
+	var points []project.ElColorPoint
+	if el, ok := a.elementColor[e]; ok {
+		o, err := a.origin(el.Parent)
+		if err != nil {
+			return false, fmt.Errorf("can not locate element [%s]: %w", e, err)
+		}
+		for _, p := range el.Points {
+			points = append(points, project.ElColorPoint{Point: p.Add(o), RGB: p.RGB})
+		}
+		if tolerance < 0 {
+			tolerance = el.Tolerance
+		}
+	} else if _, ok := a.elementPoint[e]; ok {
+		if rgb == "" {
+			return false, fmt.Errorf("can not match color of element [%s]: %w", e, NewArgsErr("#RRGGBB", rgb))
+		}
+		p, err := a.PointE(e)
+		if err != nil {
+			return false, err
+		}
+		points = []project.ElColorPoint{{Point: p}}
+	} else {
+		return false, fmt.Errorf("point or color element [%s] undefiend", e)
+	}
+	if tolerance < 0 {
+		tolerance = 0
+	}
+	if rgb != "" {
+		c, err := project.ParseColor(rgb)
+		if err != nil {
+			return false, fmt.Errorf("can not match color of element [%s]: %w", e, NewArgsErr("#RRGGBB", rgb))
+		}
+		ps := make([]project.ElColorPoint, len(points))
+		for i, p := range points {
+			ps[i] = project.ElColorPoint{Point: p.Point, RGB: c}
+		}
+		points = ps
+	}
+	frame, err := a.GetScreen()
+	if err != nil {
+		return false, fmt.Errorf("can not match color of element [%s]: %w", e, err)
 	}
-	subImg := im.(*image.YCbCr).SubImage(r)
-	buf.Reset()
-	err = jpeg.Encode(buf, subImg, &jpeg.Options{Quality: 100})
+	defer a.release(frame)
+	im, err := frame.Image()
+	if err != nil {
+		return false, fmt.Errorf("can not match color of element [%s]: %w", e, err)
+	}
+	for _, p := range points {
+		if !p.In(im.Bounds()) {
+			return false, fmt.Errorf("element [%s] point %v out of screen %v", e, p.Point, im.Bounds())
+		}
+		if !cv.ColorMatch(im.At(p.X, p.Y), p.RGB, tolerance) {
+			return false, nil
+		}
+	}
+	return true, nil
+}
+
+func (a *apiImgImpl) CurrentScene() (string, error) {
+	if a.frame == nil {
+		err := a.Lock()
+		if err != nil {
+			return "", fmt.Errorf("can not get current scene: %w", err)
+		}
+		defer a.Unlock()
+	}
+	best, bestN := "", 0
+	for _, s := range a.scenes {
+		ok, err := a.matchScene(s)
+		if err != nil {
+			return "", fmt.Errorf("can not get current scene: %w", err)
+		}
+		if ok && len(s.Require) > bestN {
+			best, bestN = s.Name, len(s.Require)
+		}
+	}
+	return best, nil
+}
+
+func (a *apiImgImpl) matchScene(s project.Scene) (bool, error) {
+	for _, c := range s.Require {
+		ok, err := a.Check(c)
+		if err != nil || !ok {
+			return false, err
+		}
+	}
+	for _, c := range s.Forbid {
+		ok, err := a.Check(c)
+		if err != nil || ok {
+			return false, err
+		}
+	}
+	return true, nil
+}
+
+func (a *apiImgImpl) Check(c project.Condition) (bool, error) {
+	if el, ok := a.elementImg[c.Element]; ok {
+		m, v, err := a.findE(c.Element)
+		if err != nil {
+			return false, err
+		}
+		return v >= threshold(el.Variants[m.Variant]), nil
+	}
+	if _, ok := a.elementColor[c.Element]; ok {
+		return a.ColorMatchE(c.Element, "", -1)
+	}
+	if _, ok := a.elementArea[c.Element]; ok {
+		text, err := a.OcrE(c.Element)
+		if err != nil {
+			return false, err
+		}
+		return strings.Contains(text, c.Text), nil
+	}
+	return false, fmt.Errorf("img, color or area element [%s] undefiend", c.Element)
+}
+
+// 识别屏幕中 r 范围内的文字
+func (a *apiImgImpl) ocr(r image.Rectangle) (string, error) {
+	frame, err := a.GetScreen()
 	if err != nil {
 		return "", err
 	}
-	str, err := a.imgHander.Ocr(buf.Bytes())
+	defer a.release(frame)
+	im, err := frame.Image()
 	if err != nil {
-		return "", fmt.Errorf("can not ocr element [%s]: %w", e, err)
+		return "", err
 	}
-	return str, nil
+	buf := bytes.NewBuffer(nil)
+	err = jpeg.Encode(buf, cv.SubImage(im, r), &jpeg.Options{Quality: 100})
+	if err != nil {
+		return "", err
+	}
+	return a.imgHander.Ocr(buf.Bytes())
 }
 
-func (a *apiImgImpl) GetScreen() ([]byte, error) {
-	if a.nowImg != nil {
-		return a.nowImg, nil
+// 如果已经锁定，返回锁定时的截图，否则重新截取一张
+func (a *apiImgImpl) GetScreen() (*Frame, error) {
+	if a.frame != nil {
+		return a.frame, nil
+	}
+	data, err := a.screencap.ToByte()
+	if err != nil {
+		return nil, err
+	}
+	return NewFrame(data), nil
+}
+
+// 释放不是由 Lock 持有的 Frame
+func (a *apiImgImpl) release(f *Frame) {
+	if f != a.frame {
+		_ = f.Close()
 	}
-	return a.screencap.ToByte()
 }
 
 func (a *apiImgImpl) Lock() error {
-	if a.nowImg != nil {
+	if a.frame != nil {
 		return errors.New("can not be locked repeatedly")
 	}
 	img, err := a.screencap.ToByte()
 	if err != nil {
 		return fmt.Errorf("failed to lock: %w", err)
 	}
-	a.nowImg = img
+	a.frame = NewFrame(img)
 	return nil
 }
 
 func (a *apiImgImpl) Unlock() error {
-	if a.nowImg == nil {
+	if a.frame == nil {
 		return errors.New("can not be unlocked repeatedly")
 	}
-	a.nowImg = nil
-	return nil
+	err := a.frame.Close()
+	a.frame = nil
+	return err
+}
+
+func NewApiImg(
+	device devices.Device,
+	elementImg map[string]project.ElImg,
+	elementArea map[string]project.ElArea,
+	elementPoint map[string]project.ElPoint,
+	elementColor map[string]project.ElColor,
+	scenes []project.Scene,
+) (ApiImg, error) {
+	return NewApiImgWithHandler(device, newImgHander(), elementImg, elementArea, elementPoint, elementColor, scenes)
 }
 
-func NewApiImg(adbCmd adb.ADBRunner, elementImg map[string]project.ElImg, elementArea map[string]project.ElArea) (ApiImg, error) {
+// 与 NewApiImg 相同，但是使用 handler 进行匹配和文字识别
+func NewApiImgWithHandler(
+	device devices.Device,
+	handler ImgHandler,
+	elementImg map[string]project.ElImg,
+	elementArea map[string]project.ElArea,
+	elementPoint map[string]project.ElPoint,
+	elementColor map[string]project.ElColor,
+	scenes []project.Scene,
+) (ApiImg, error) {
 	a := apiImgImpl{
-		elementMat: make(map[string]gocv.Mat),
-		imgHander:  newImgHander(),
-		screencap:  &screencapToolImpl{adbCmd: adbCmd},
+		elementMat:   make(map[string][]gocv.Mat),
+		elementImg:   make(map[string]project.ElImg),
+		elementArea:  elementArea,
+		elementPoint: elementPoint,
+		elementColor: elementColor,
+		scenes:       scenes,
+		lastFound:    make(map[string]lastMatch),
+		imgHander:    handler,
+		screencap:    &screencapToolImpl{device: device},
 	}
 	for k, e := range elementImg {
-		mat, err := gocv.IMDecode(e.Img, gocv.IMReadUnchanged)
-		if err != nil {
-			return nil, fmt.Errorf("failed to decode from [%s] to gocv.Mat: %w", k, err)
+		// 没有变体时，元素本身就是唯一的变体
+		if len(e.Variants) == 0 {
+			e.Variants = []project.ElImgVariant{{
+				Img:       e.Img,
+				Offset:    e.Offset,
+				Threshold: e.Threshold,
+			}}
+		}
+		a.elementImg[k] = e
+		for _, v := range e.Variants {
+			mat, err := gocv.IMDecode(v.Img, gocv.IMReadUnchanged)
+			if err != nil {
+				return nil, fmt.Errorf("failed to decode variant [%s] of [%s] to gocv.Mat: %w", v.Name, k, err)
+			}
+			a.elementMat[k] = append(a.elementMat[k], mat)
+		}
+	}
+	for _, s := range scenes {
+		for _, c := range append(append([]project.Condition{}, s.Require...), s.Forbid...) {
+			_, isImg := elementImg[c.Element]
+			_, isColor := elementColor[c.Element]
+			_, isArea := elementArea[c.Element]
+			if !isImg && !isColor && !isArea {
+				return nil, fmt.Errorf("scene [%s]: img, color or area element [%s] undefiend", s.Name, c.Element)
+			}
 		}
-		a.elementMat[k] = mat
 	}
 	return &a, nil
 }
@@ -0,0 +1,328 @@
+package api_test
+
+import (
+	"bytes"
+	"errors"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/png"
+	"testing"
+	"time"
+
+	"github.com/HumXC/give-me-time/cv"
+	"github.com/HumXC/give-me-time/devices"
+	"github.com/HumXC/give-me-time/engine/api"
+	"github.com/HumXC/give-me-time/engine/project"
+	"gocv.io/x/gocv"
+)
+
+// 纯色的图片，用作截图和模板
+func pngOf(t *testing.T, w, h int, c color.RGBA) []byte {
+	img := image.NewRGBA(image.Rect(0, 0, w, h))
+	draw.Draw(img, img.Bounds(), image.NewUniform(c), image.ZP, draw.Src)
+	buf := new(bytes.Buffer)
+	err := png.Encode(buf, img)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+// 根据截图的宽和模板的宽返回匹配的结果，不会真正进行匹配
+// scores 的 key 是截图的宽，值的 key 是模板的宽
+type stubHandler struct {
+	scores map[int]map[int]float32
+	at     image.Point
+	text   string
+	finds  int
+}
+
+func (s *stubHandler) Find(img gocv.Mat, tmpl gocv.Mat, opt cv.MatchOption) (float32, image.Point, error) {
+	s.finds++
+	v, ok := s.scores[img.Cols()][tmpl.Cols()]
+	if !ok {
+		return 0, image.ZP, errors.New("stub error")
+	}
+	return v, s.at, nil
+}
+
+func (s *stubHandler) FindFeature(img gocv.Mat, tmpl gocv.Mat, feature cv.Feature, points []image.Point) (float32, []image.Point, error) {
+	return 0, nil, cv.ErrTmplTooSmall
+}
+
+func (s *stubHandler) Ocr(img []byte) (string, error) {
+	return s.text, nil
+}
+
+func TestFindVariant(t *testing.T) {
+	d, err := devices.NewFakeDevice(devices.FakeConfig{Start: "home"}, map[string][]byte{
+		"home": pngOf(t, 100, 50, color.RGBA{A: 255}),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// strict 的得分最高但是没有达到自己的阈值，loose 达到了自己的阈值，broken 出错时被跳过
+	h := &stubHandler{scores: map[int]map[int]float32{100: {10: 0.9, 20: 0.7}}, at: image.Pt(1, 2)}
+	el := project.ElImg{Variants: []project.ElImgVariant{
+		{Name: "strict", Img: pngOf(t, 10, 10, color.RGBA{A: 255}), Threshold: 0.95},
+		{Name: "loose", Img: pngOf(t, 20, 10, color.RGBA{A: 255}), Threshold: 0.6, Offset: image.Pt(3, 3)},
+		{Name: "broken", Img: pngOf(t, 30, 10, color.RGBA{A: 255})},
+	}}
+	a, err := api.NewApiImgWithHandler(d, h, map[string]project.ElImg{"btn": el}, nil, nil, nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	p, v, name, err := a.FindVariantE("btn")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if name != "loose" || v != 0.7 || !p.Eq(image.Pt(1, 2)) {
+		t.Errorf("want: loose 0.7 (1,2), got: %s %f %v", name, v, p)
+	}
+	ok, err := a.Check(project.Condition{Element: "btn"})
+	if err != nil || !ok {
+		t.Errorf("want: true, got: %v, %v", ok, err)
+	}
+	p, err = a.PointE("btn")
+	if err != nil || !p.Eq(image.Pt(4, 5)) {
+		t.Errorf("want: (4,5), got: %v, %v", p, err)
+	}
+
+	// 都没有达到阈值时返回得分最高的变体，PointE 返回 ErrNotFound
+	h.scores[100] = map[int]float32{10: 0.9, 20: 0.5}
+	_, v, name, err = a.FindVariantE("btn")
+	if err != nil || name != "strict" || v != 0.9 {
+		t.Errorf("want: strict 0.9, got: %s %f %v", name, v, err)
+	}
+	_, err = a.PointE("btn")
+	if !errors.Is(err, api.ErrNotFound) {
+		t.Errorf("want: %v, got: %v", api.ErrNotFound, err)
+	}
+
+	// 所有的变体都出错时返回错误
+	h.scores[100] = nil
+	_, _, err = a.FindE("btn")
+	if err == nil || errors.Is(err, api.ErrNotFound) {
+		t.Errorf("case [all variants error] should be an error, but got: %v", err)
+	}
+}
+
+// 记录截图次数的模拟设备
+type countDevice struct {
+	*devices.FakeDevice
+	shots int
+}
+
+func (d *countDevice) Screenshot() ([]byte, error) {
+	d.shots++
+	return d.FakeDevice.Screenshot()
+}
+
+var (
+	red  = color.RGBA{R: 255, A: 255}
+	blue = color.RGBA{B: 255, A: 255}
+)
+
+// 红色屏幕 "a" 的宽是 100，蓝色屏幕 "b" 的宽是 120
+// panel 只在 "a" 上找到，btn 是相对于 panel 的 point 元素，title 是相对于 panel 的 area 元素
+func newTestImg(t *testing.T, sequence ...string) (api.ApiImg, *countDevice, *stubHandler) {
+	f, err := devices.NewFakeDevice(devices.FakeConfig{Start: "a", Sequence: sequence}, map[string][]byte{
+		"a": pngOf(t, 100, 50, red),
+		"b": pngOf(t, 120, 50, blue),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	d := &countDevice{FakeDevice: f}
+	h := &stubHandler{scores: map[int]map[int]float32{100: {10: 0.9}, 120: {10: 0.1}}, at: image.Pt(10, 20), text: "开始游戏"}
+	a, err := api.NewApiImgWithHandler(d, h,
+		map[string]project.ElImg{"panel": {Img: pngOf(t, 10, 10, red)}},
+		map[string]project.ElArea{"panel.title": {P1: image.Pt(0, 0), P2: image.Pt(20, 10), Parent: "panel"}},
+		map[string]project.ElPoint{"panel.btn": {Point: image.Pt(5, 5), Parent: "panel"}},
+		map[string]project.ElColor{
+			"red":  {Points: []project.ElColorPoint{{Point: image.Pt(1, 1), RGB: red}}},
+			"blue": {Points: []project.ElColorPoint{{Point: image.Pt(1, 1), RGB: blue}}},
+		},
+		[]project.Scene{
+			{Name: "home", Require: []project.Condition{{Element: "red"}}},
+			{Name: "home2", Require: []project.Condition{{Element: "red"}}},
+			{Name: "menu", Require: []project.Condition{{Element: "red"}, {Element: "panel.title", Text: "开始"}}},
+			{Name: "shop", Require: []project.Condition{{Element: "red"}, {Element: "panel"}, {Element: "blue"}}},
+		},
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return a, d, h
+}
+
+func TestFrame(t *testing.T) {
+	f := api.NewFrame(pngOf(t, 10, 10, red))
+	im1, err := f.Image()
+	if err != nil {
+		t.Fatal(err)
+	}
+	im2, _ := f.Image()
+	if im1 != im2 {
+		t.Errorf("image should be decoded only once")
+	}
+	m1, err := f.Mat()
+	if err != nil {
+		t.Fatal(err)
+	}
+	m2, _ := f.Mat()
+	if m1.Ptr() != m2.Ptr() || m1.Cols() != 10 {
+		t.Errorf("mat should be decoded only once")
+	}
+	if err = f.Close(); err != nil {
+		t.Error(err)
+	}
+	if err = f.Close(); err != nil {
+		t.Error("close twice:", err)
+	}
+}
+
+func TestLockedFrame(t *testing.T) {
+	a, d, h := newTestImg(t)
+	err := a.Lock()
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 锁定时同一帧只截图一次，同一个 img 元素只匹配一次
+	for i := 0; i < 2; i++ {
+		p, err := a.PointE("panel.btn")
+		if err != nil || !p.Eq(image.Pt(15, 25)) {
+			t.Errorf("want: (15,25), got: %v, %v", p, err)
+		}
+		_, err = a.AreaE("panel.title")
+		if err != nil {
+			t.Error(err)
+		}
+	}
+	c, err := a.PixelAt(1, 1)
+	if err != nil || c != red {
+		t.Errorf("want: %v, got: %v, %v", red, c, err)
+	}
+	if d.shots != 1 || h.finds != 1 {
+		t.Errorf("want: 1 screenshot and 1 find, got: %d, %d", d.shots, h.finds)
+	}
+	if a.Lock() == nil {
+		t.Errorf("case [lock twice] should be an error, but not")
+	}
+	err = a.Unlock()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if a.Unlock() == nil {
+		t.Errorf("case [unlock twice] should be an error, but not")
+	}
+	// 没有锁定时每次都重新截图
+	_, _ = a.PixelAt(1, 1)
+	_, _ = a.PixelAt(1, 1)
+	if d.shots != 3 {
+		t.Errorf("want: 3 screenshots, got: %d", d.shots)
+	}
+}
+
+func TestPixelAndColor(t *testing.T) {
+	a, _, _ := newTestImg(t)
+	_, err := a.PixelAt(100, 0)
+	if !errors.Is(err, api.ErrArgs) {
+		t.Errorf("want: %v, got: %v", api.ErrArgs, err)
+	}
+	cases := []struct {
+		e, rgb    string
+		tolerance int
+		want      bool
+	}{
+		{"red", "", -1, true},
+		{"blue", "", -1, false},
+		{"red", "#0000FF", -1, false},
+		{"blue", "#F00000", 15, true},
+		{"panel.btn", "#FF0000", 0, true},
+	}
+	for _, c := range cases {
+		ok, err := a.ColorMatchE(c.e, c.rgb, c.tolerance)
+		if err != nil || ok != c.want {
+			t.Errorf("case [%s %s %d] want: %v, got: %v, %v", c.e, c.rgb, c.tolerance, c.want, ok, err)
+		}
+	}
+	_, err = a.ColorMatchE("panel.btn", "", -1)
+	if !errors.Is(err, api.ErrArgs) {
+		t.Errorf("want: %v, got: %v", api.ErrArgs, err)
+	}
+}
+
+func TestLastFound(t *testing.T) {
+	a, _, h := newTestImg(t)
+	// LastFoundTTL 内子元素使用父元素最后一次找到的位置
+	for i := 0; i < 2; i++ {
+		p, err := a.PointE("panel.btn")
+		if err != nil || !p.Eq(image.Pt(15, 25)) {
+			t.Errorf("want: (15,25), got: %v, %v", p, err)
+		}
+	}
+	if h.finds != 1 {
+		t.Errorf("want: 1 find, got: %d", h.finds)
+	}
+	time.Sleep(api.LastFoundTTL + 100*time.Millisecond)
+	_, err := a.PointE("panel.btn")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if h.finds != 2 {
+		t.Errorf("want: 2 finds after LastFoundTTL, got: %d", h.finds)
+	}
+	// PointE 不使用最后一次找到的位置
+	_, _ = a.PointE("panel")
+	if h.finds != 3 {
+		t.Errorf("want: 3 finds, got: %d", h.finds)
+	}
+}
+
+func TestParentLeft(t *testing.T) {
+	a, _, _ := newTestImg(t, "a", "b")
+	_, err := a.PointE("panel.btn")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// panel 离开屏幕之后，子元素不能再使用最后一次找到的位置
+	ok, err := a.Check(project.Condition{Element: "panel"})
+	if err != nil || ok {
+		t.Fatalf("want: false, got: %v, %v", ok, err)
+	}
+	_, err = a.PointE("panel.btn")
+	if !errors.Is(err, api.ErrNotFound) {
+		t.Errorf("want: %v, got: %v", api.ErrNotFound, err)
+	}
+	_, err = a.OcrE("panel.title")
+	if !errors.Is(err, api.ErrNotFound) {
+		t.Errorf("want: %v, got: %v", api.ErrNotFound, err)
+	}
+}
+
+func TestCurrentScene(t *testing.T) {
+	a, _, h := newTestImg(t)
+	// home, home2 和 menu 都符合，menu 的 Require 最多
+	s, err := a.CurrentScene()
+	if err != nil || s != "menu" {
+		t.Errorf("want: menu, got: %s, %v", s, err)
+	}
+	// Require 一样多时使用先定义的
+	h.text = "商店"
+	s, err = a.CurrentScene()
+	if err != nil || s != "home" {
+		t.Errorf("want: home, got: %s, %v", s, err)
+	}
+	ok, err := a.Check(project.Condition{Element: "panel.title", Text: "商店"})
+	if err != nil || !ok {
+		t.Errorf("want: true, got: %v, %v", ok, err)
+	}
+	// 所有的 Scene 使用同一张截图
+	a2, d, _ := newTestImg(t)
+	_, _ = a2.CurrentScene()
+	if d.shots != 1 {
+		t.Errorf("want: 1 screenshot, got: %d", d.shots)
+	}
+}
@@ -0,0 +1,35 @@
+package api
+
+import "fmt"
+
+type ApiOption interface {
+	// 返回一个选项的值
+	GetOption(name string) (any, error)
+	// 返回所有选项的值，修改返回值不会影响原来的选项
+	GetOptions() map[string]any
+}
+
+type apiOptionImpl struct {
+	values map[string]any
+}
+
+func (a *apiOptionImpl) GetOption(name string) (any, error) {
+	v, ok := a.values[name]
+	if !ok {
+		return nil, fmt.Errorf("option [%s] undefiend", name)
+	}
+	return v, nil
+}
+
+func (a *apiOptionImpl) GetOptions() map[string]any {
+	m := make(map[string]any, len(a.values))
+	for k, v := range a.values {
+		m[k] = v
+	}
+	return m
+}
+
+// values 是 project.ParseOption 的返回值
+func NewApiOption(values map[string]any) ApiOption {
+	return &apiOptionImpl{values: values}
+}
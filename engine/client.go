@@ -3,12 +3,54 @@ package engine
 import (
 	"os"
 
-	"github.com/HumXC/adb-helper"
+	"github.com/HumXC/give-me-time/devices"
+	"github.com/HumXC/give-me-time/engine/api"
 	"github.com/HumXC/give-me-time/engine/project"
 )
 
+// 一个设备上运行的工程，Adb, Img 和 Option 会通过 Server 提供给运行环境中的脚本
 type Client struct {
 	Info    *project.Info
-	Device  adb.Device
+	Device  devices.Device
 	LogFile *os.File
+	Adb     api.ApiAdb
+	Img     api.ApiImg
+	Option  api.ApiOption
+	// 工程中定义的选项，用于隐藏 secret 类型的选项
+	OptionDefs []project.Option
+}
+
+// 提供给脚本的方法，参考 NewServer
+// 不包含 Shell，脚本不能在设备上执行任意的命令
+func (c *Client) Methods() map[string]any {
+	return map[string]any{
+		"Press": c.Adb.Press,
+		"Swipe": func(x1, y1, x2, y2, duration int) error {
+			_, _, _, err := c.Adb.Swipe(x1, y1).To(x2, y2).Action(duration)
+			return err
+		},
+		"Key": c.Adb.Key,
+		// 按下一个元素，等价于 PointE 之后 Press
+		"PressE": func(e string, duration int) error {
+			p, err := c.Img.PointE(e)
+			if err != nil {
+				return err
+			}
+			return c.Adb.Press(p.X, p.Y, duration)
+		},
+		"FindE":        c.Img.FindE,
+		"FindVariantE": c.Img.FindVariantE,
+		"Ocr":          c.Img.Ocr,
+		"OcrE":         c.Img.OcrE,
+		"PixelAt":      c.Img.PixelAt,
+		"ColorMatchE":  c.Img.ColorMatchE,
+		"CurrentScene": c.Img.CurrentScene,
+		"Check":        c.Img.Check,
+		"PointE":       c.Img.PointE,
+		"AreaE":        c.Img.AreaE,
+		"Lock":         c.Img.Lock,
+		"Unlock":       c.Img.Unlock,
+		"GetOption":    c.Option.GetOption,
+		"GetOptions":   c.Option.GetOptions,
+	}
 }
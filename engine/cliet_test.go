@@ -1,18 +1,140 @@
 package engine_test
 
 import (
+	"bytes"
+	"context"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/png"
+	"os"
 	"testing"
+	"testing/fstest"
 
-	"github.com/HumXC/adb-helper"
+	"github.com/HumXC/give-me-time/devices"
+	"github.com/HumXC/give-me-time/engine"
+	"github.com/HumXC/give-me-time/engine/api"
+	"github.com/HumXC/give-me-time/engine/project"
 )
 
-const TestProject = "../test/project-setting"
+// 在 home 屏幕上按下 start 之后进入 done 屏幕
+// home 是 cv 测试中的 big.png，start 是其中的 small.png，位于 (103, 174)，大小为 107x26
+// done 是纯红色的屏幕
+const (
+	testInfo = `
+name: e2e
+runtime:
+    name: sh
+    run: echo [HOST]:[PORT]
+resolution:
+    width: 1556
+    height: 1015
+`
+	testElement = `
+- name: start
+  img: ../img/start.png
+  offset: {x: 53, y: 13}
+- name: red
+  color:
+      - x: 10
+        y: 10
+        rgb: "#FF0000"
+`
+	testScene = `
+- name: home
+  require:
+      - element: start
+- name: done
+  require:
+      - element: red
+`
+	testFlow = `
+name: e2e
+interval: 10
+max_steps: 10
+states:
+    - name: home
+      scene: home
+      transitions:
+          - do:
+                - press: start
+    - name: done
+      scene: done
+      end: true
+`
+	testFake = `
+start: home
+screens:
+    home: screens/home.png
+    done: screens/done.png
+transitions:
+    - from: home
+      tap: {x1: 103, y1: 174, x2: 210, y2: 200}
+      to: done
+`
+)
 
-func TestLoad(t *testing.T) {
-	server := adb.DefaultServer()
-	_, err := server.Devices()
+func TestRunProjectFakeDevice(t *testing.T) {
+	home, err := os.ReadFile("../cv/test/big.png")
 	if err != nil {
 		t.Fatal(err)
 	}
-
+	start, err := os.ReadFile("../cv/test/small.png")
+	if err != nil {
+		t.Fatal(err)
+	}
+	red := image.NewRGBA(image.Rect(0, 0, 1556, 1015))
+	draw.Draw(red, red.Bounds(), image.NewUniform(color.RGBA{255, 0, 0, 255}), image.ZP, draw.Src)
+	done := new(bytes.Buffer)
+	err = png.Encode(done, red)
+	if err != nil {
+		t.Fatal(err)
+	}
+	fsys := fstest.MapFS{
+		"info.yaml":         {Data: []byte(testInfo)},
+		"element/main.yaml": {Data: []byte(testElement)},
+		"scene/main.yaml":   {Data: []byte(testScene)},
+		"flow/main.yaml":    {Data: []byte(testFlow)},
+		"img/start.png":     {Data: start},
+		"fake.yaml":         {Data: []byte(testFake)},
+		"screens/home.png":  {Data: home},
+		"screens/done.png":  {Data: done.Bytes()},
+	}
+	p, err := project.Open(fsys)
+	if err != nil {
+		t.Fatal(err)
+	}
+	d, err := devices.LoadFake(fsys, "fake.yaml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	size, err := d.ScreenSize()
+	if err != nil {
+		t.Fatal(err)
+	}
+	elImg, elArea, elPoint, elColor, err := p.ParseElement(size)
+	if err != nil {
+		t.Fatal(err)
+	}
+	img, err := api.NewApiImg(d, elImg, elArea, elPoint, elColor, p.Scene)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, f := range p.Flow {
+		r, err := engine.NewFlowRunner(f, api.NewApiAdb(d), img, elImg, elArea, elPoint, elColor, p.Scene, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		err = r.Run(context.Background())
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	if d.Screen() != "done" {
+		t.Errorf("want: done, got: %s", d.Screen())
+	}
+	es := d.Events()
+	if len(es) != 1 || es[0].Type != devices.FakeTap || es[0].Screen != "home" {
+		t.Errorf("unexpected events: %+v", es)
+	}
 }
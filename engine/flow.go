@@ -0,0 +1,383 @@
+package engine
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"image"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/HumXC/give-me-time/devices"
+	"github.com/HumXC/give-me-time/engine/api"
+	"github.com/HumXC/give-me-time/engine/project"
+	"golang.org/x/exp/slog"
+)
+
+var ErrFlowStuck = errors.New("flow is stuck")
+
+// 设备断开连接后重连的次数和重连之间的等待时间，第一次重连不等待，之后每次等待的时间翻倍
+// 每次重连最多等待 ReconnectTimeout
+const (
+	ReconnectAttempts = 5
+	ReconnectBackoff  = time.Second
+	ReconnectTimeout  = 30 * time.Second
+)
+
+// FlowRunner 根据 project.Flow 驱动 ApiAdb 和 ApiImg 执行任务
+type FlowRunner struct {
+	flow    project.Flow
+	adb     api.ApiAdb
+	img     api.ApiImg
+	elImg   map[string]project.ElImg
+	elArea  map[string]project.ElArea
+	elPoint map[string]project.ElPoint
+	log     *slog.Logger
+	// 为 nil 时设备断开连接会让 Flow 失败
+	reconnect func(ctx context.Context) error
+	backoff   time.Duration
+}
+
+// 设置设备断开连接时的重连函数
+// 执行 Flow 时遇到 api.AdbError 会使用 reconnect 重连，重连成功后从当前状态继续执行
+func (r *FlowRunner) OnDisconnect(reconnect func(ctx context.Context) error) {
+	r.reconnect = reconnect
+}
+
+// 判断 err 是否是设备断开连接，如果是则尝试重连，返回 nil 表示已经重连，可以继续执行
+func (r *FlowRunner) handleDisconnect(ctx context.Context, err error) error {
+	var adbErr *api.AdbError
+	if r.reconnect == nil || !errors.As(err, &adbErr) {
+		return err
+	}
+	wait := r.backoff
+	for i := 0; i < ReconnectAttempts; i++ {
+		// 第一次立即重连
+		if i != 0 {
+			e := sleep(ctx, int(wait/time.Millisecond))
+			if e != nil {
+				return e
+			}
+			wait *= 2
+		}
+		r.log.Warn("device disconnected, reconnecting", "attempt", i+1, "err", err)
+		rctx, cancel := context.WithTimeout(ctx, ReconnectTimeout)
+		e := r.reconnect(rctx)
+		cancel()
+		if e == nil {
+			r.log.Info("device reconnected")
+			return nil
+		}
+		r.log.Warn("reconnect failed", "err", e)
+	}
+	return fmt.Errorf("failed to reconnect after %d attempts: %w", ReconnectAttempts, err)
+}
+
+// 执行 Flow 直到进入 End 状态
+// 同一个状态连续出现超过 Retry 次时执行 Fallback，Fallback 之后依然没有变化则返回 ErrFlowStuck
+func (r *FlowRunner) Run(ctx context.Context) error {
+	makeErr := func(err error) error {
+		return fmt.Errorf("flow [%s] failed: %w", r.flow.Name, err)
+	}
+	last := ""
+	stuck := 0
+	fallbackUsed := false
+	for step := 0; step < r.flow.MaxSteps; step++ {
+		if step != 0 {
+			err := sleep(ctx, r.flow.Interval)
+			if err != nil {
+				return makeErr(err)
+			}
+		}
+		state, t, err := r.step()
+		if err != nil {
+			err = r.handleDisconnect(ctx, err)
+			if err != nil {
+				return makeErr(err)
+			}
+			continue
+		}
+		name := ""
+		if state != nil {
+			name = state.Name
+		}
+		if step != 0 && name == last {
+			stuck++
+		} else {
+			last, stuck, fallbackUsed = name, 0, false
+		}
+		r.log.Info("flow step", "step", step, "state", name, "stuck", stuck)
+		if state != nil && state.End {
+			return nil
+		}
+
+		retry, fallback := r.flow.Retry, r.flow.Fallback
+		if state != nil {
+			retry = state.Retry
+			if len(state.Fallback) != 0 {
+				fallback = state.Fallback
+			}
+		}
+		if stuck > retry {
+			if fallbackUsed || len(fallback) == 0 {
+				return makeErr(fmt.Errorf("%w in state [%s]", ErrFlowStuck, name))
+			}
+			r.log.Warn("run fallback", "state", name)
+			err := r.do(ctx, fallback)
+			if err != nil && !errors.Is(err, api.ErrNotFound) {
+				err = r.handleDisconnect(ctx, err)
+				if err != nil {
+					return makeErr(err)
+				}
+			}
+			stuck, fallbackUsed = 0, true
+			continue
+		}
+		if t == nil {
+			continue
+		}
+		// 要按下的 img 元素没有在屏幕上找到，这不会让 Flow 失败，而是算作一次重试
+		err = r.do(ctx, t.Do)
+		if errors.Is(err, api.ErrNotFound) {
+			r.log.Warn("action skipped", "state", name, "err", err)
+			continue
+		}
+		if err != nil {
+			err = r.handleDisconnect(ctx, err)
+			if err != nil {
+				return makeErr(err)
+			}
+		}
+	}
+	return makeErr(fmt.Errorf("exceeded max steps [%d]", r.flow.MaxSteps))
+}
+
+// 锁定屏幕，判断当前所处的状态和要执行的 Transition
+// 无法判断状态或者没有满足条件的 Transition 时，对应的返回值为 nil
+func (r *FlowRunner) step() (*project.State, *project.Transition, error) {
+	err := r.img.Lock()
+	if err != nil {
+		return nil, nil, err
+	}
+	defer r.img.Unlock()
+	state, err := r.detect()
+	if err != nil || state == nil {
+		return nil, nil, err
+	}
+	for i := 0; i < len(state.Transitions); i++ {
+		t := &state.Transitions[i]
+		if t.If == nil {
+			return state, t, nil
+		}
+		ok, err := r.compare(*t.If)
+		if err != nil {
+			return nil, nil, err
+		}
+		if ok {
+			return state, t, nil
+		}
+	}
+	return state, nil, nil
+}
+
+// 按顺序返回第一个符合的状态
+func (r *FlowRunner) detect() (*project.State, error) {
+	scene := ""
+	sceneDone := false
+	for i := 0; i < len(r.flow.States); i++ {
+		s := &r.flow.States[i]
+		if s.Scene != "" {
+			if !sceneDone {
+				var err error
+				scene, err = r.img.CurrentScene()
+				if err != nil {
+					return nil, err
+				}
+				sceneDone = true
+			}
+			if scene != s.Scene {
+				continue
+			}
+		}
+		ok := true
+		for _, c := range s.When {
+			var err error
+			ok, err = r.img.Check(c)
+			if err != nil {
+				return nil, err
+			}
+			if !ok {
+				break
+			}
+		}
+		if ok {
+			return s, nil
+		}
+	}
+	return nil, nil
+}
+
+func (r *FlowRunner) compare(c project.Compare) (bool, error) {
+	text, err := r.img.OcrE(c.Ocr)
+	if err != nil {
+		return false, err
+	}
+	text = strings.TrimSpace(text)
+	switch c.Op {
+	case project.OpEq:
+		return text == c.Value, nil
+	case project.OpNe:
+		return text != c.Value, nil
+	case project.OpContains:
+		return strings.Contains(text, c.Value), nil
+	}
+	// 识别结果中的数字可能带有千位分隔符
+	clean := strings.NewReplacer(",", "", " ", "").Replace(text)
+	a, err := strconv.ParseFloat(clean, 64)
+	if err != nil {
+		return false, fmt.Errorf("ocr result [%s] of [%s] is not a number", text, c.Ocr)
+	}
+	b, err := strconv.ParseFloat(c.Value, 64)
+	if err != nil {
+		return false, fmt.Errorf("compare value [%s] is not a number", c.Value)
+	}
+	switch c.Op {
+	case project.OpLt:
+		return a < b, nil
+	case project.OpLe:
+		return a <= b, nil
+	case project.OpGt:
+		return a > b, nil
+	case project.OpGe:
+		return a >= b, nil
+	}
+	return false, fmt.Errorf("compare op [%s] undefined", c.Op)
+}
+
+func (r *FlowRunner) do(ctx context.Context, as []project.Action) error {
+	for _, a := range as {
+		var err error
+		switch {
+		case a.Press != "":
+			var p image.Point
+			p, err = r.img.PointE(a.Press)
+			if err == nil {
+				err = r.adb.Press(p.X, p.Y, 0)
+			}
+		case a.Swipe != nil:
+			s := a.Swipe
+			_, _, _, err = r.adb.Swipe(s.X1, s.Y1).To(s.X2, s.Y2).Action(s.Duration)
+		case a.Wait != 0:
+			err = sleep(ctx, a.Wait)
+		case a.Back:
+			err = r.adb.Key(devices.KeyBack)
+		case a.Restart != "":
+			err = r.adb.Restart(a.Restart)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func sleep(ctx context.Context, ms int) error {
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-time.After(time.Duration(ms) * time.Millisecond):
+		return nil
+	}
+}
+
+// 创建一个 FlowRunner，会检查 Flow 中使用的元素和 Scene 是否已经定义，并为 Flow 赋予默认值
+// When 中的元素必须是 img，color 或者 area 元素，area 元素的条件必须有 Text
+// log 为 nil 时使用 slog.Default()
+func NewFlowRunner(
+	flow project.Flow,
+	adb api.ApiAdb,
+	img api.ApiImg,
+	elImg map[string]project.ElImg,
+	elArea map[string]project.ElArea,
+	elPoint map[string]project.ElPoint,
+	elColor map[string]project.ElColor,
+	scenes []project.Scene,
+	log *slog.Logger,
+) (*FlowRunner, error) {
+	err := project.VerifyFlow(flow)
+	if err != nil {
+		return nil, err
+	}
+	// 复制一份 States，避免 SetFlowDefault 修改调用者的数据
+	flow.States = append([]project.State(nil), flow.States...)
+	project.SetFlowDefault(&flow)
+	if log == nil {
+		log = slog.Default()
+	}
+	r := &FlowRunner{
+		flow:    flow,
+		adb:     adb,
+		img:     img,
+		elImg:   elImg,
+		elArea:  elArea,
+		elPoint: elPoint,
+		log:     log,
+		backoff: ReconnectBackoff,
+	}
+
+	sceneNames := make(map[string]struct{})
+	for _, s := range scenes {
+		sceneNames[s.Name] = struct{}{}
+	}
+	checkActions := func(as []project.Action) error {
+		for _, a := range as {
+			if a.Press == "" {
+				continue
+			}
+			_, isImg := elImg[a.Press]
+			_, isArea := elArea[a.Press]
+			_, isPoint := elPoint[a.Press]
+			if !isImg && !isArea && !isPoint {
+				return fmt.Errorf("img, area or point element [%s] undefiend", a.Press)
+			}
+		}
+		return nil
+	}
+	err = checkActions(flow.Fallback)
+	if err != nil {
+		return nil, err
+	}
+	for _, s := range flow.States {
+		if _, ok := sceneNames[s.Scene]; s.Scene != "" && !ok {
+			return nil, fmt.Errorf("state [%s]: scene [%s] undefiend", s.Name, s.Scene)
+		}
+		for _, c := range s.When {
+			_, isImg := elImg[c.Element]
+			_, isColor := elColor[c.Element]
+			_, isArea := elArea[c.Element]
+			if !isImg && !isColor && !isArea {
+				return nil, fmt.Errorf("state [%s]: img, color or area element [%s] undefiend", s.Name, c.Element)
+			}
+			if isArea && c.Text == "" {
+				return nil, fmt.Errorf("state [%s]: condition of area element [%s] must have [text]", s.Name, c.Element)
+			}
+		}
+		for _, t := range s.Transitions {
+			if t.If != nil {
+				if _, ok := elArea[t.If.Ocr]; !ok {
+					return nil, fmt.Errorf("state [%s]: area element [%s] undefiend", s.Name, t.If.Ocr)
+				}
+			}
+			err = checkActions(t.Do)
+			if err != nil {
+				return nil, fmt.Errorf("state [%s]: %w", s.Name, err)
+			}
+		}
+		err = checkActions(s.Fallback)
+		if err != nil {
+			return nil, fmt.Errorf("state [%s]: %w", s.Name, err)
+		}
+	}
+	return r, nil
+}
@@ -0,0 +1,247 @@
+package engine_test
+
+import (
+	"context"
+	"errors"
+	"image"
+	"image/color"
+	"testing"
+
+	"github.com/HumXC/give-me-time/devices"
+	"github.com/HumXC/give-me-time/engine"
+	"github.com/HumXC/give-me-time/engine/api"
+	"github.com/HumXC/give-me-time/engine/project"
+)
+
+// 按顺序返回 scenes 中的 Scene，每次 Lock 前进一步
+type fakeImg struct {
+	scenes []string
+	ocr    string
+	points map[string]image.Point
+	i      int
+}
+
+func (f *fakeImg) scene() string {
+	if f.i >= len(f.scenes) {
+		return f.scenes[len(f.scenes)-1]
+	}
+	return f.scenes[f.i]
+}
+func (f *fakeImg) FindE(e string) (image.Point, float32, error) { return image.Pt(10, 10), 1, nil }
+func (f *fakeImg) FindVariantE(e string) (image.Point, float32, string, error) {
+	return image.Pt(10, 10), 1, "", nil
+}
+func (f *fakeImg) Ocr(x1, y1, x2, y2 int) (string, error) { return f.ocr, nil }
+func (f *fakeImg) OcrE(e string) (string, error)          { return f.ocr, nil }
+func (f *fakeImg) PixelAt(x, y int) (color.RGBA, error)   { return color.RGBA{}, nil }
+func (f *fakeImg) ColorMatchE(e string, rgb string, tolerance int) (bool, error) {
+	return false, nil
+}
+func (f *fakeImg) CurrentScene() (string, error) { return f.scene(), nil }
+func (f *fakeImg) Check(c project.Condition) (bool, error) {
+	return c.Element == f.scene(), nil
+}
+func (f *fakeImg) PointE(e string) (image.Point, error) {
+	p, ok := f.points[e]
+	if !ok {
+		return image.ZP, api.ErrNotFound
+	}
+	return p, nil
+}
+func (f *fakeImg) AreaE(e string) (image.Rectangle, error) { return image.Rectangle{}, nil }
+func (f *fakeImg) Lock() error                             { return nil }
+func (f *fakeImg) Unlock() error {
+	f.i++
+	return nil
+}
+
+type fakeAdb struct {
+	presses []image.Point
+	keys    []string
+	apps    []string
+	// 前 drop 次 Press 会返回 api.AdbError
+	drop int
+}
+
+func (f *fakeAdb) Press(x, y, duration int) error {
+	if f.drop > 0 {
+		f.drop--
+		return &api.AdbError{Err: errors.New("device offline")}
+	}
+	f.presses = append(f.presses, image.Pt(x, y))
+	return nil
+}
+func (f *fakeAdb) Swipe(x, y int) api.InputHandlerSwipeTo { return nil }
+func (f *fakeAdb) Key(key string) error {
+	f.keys = append(f.keys, key)
+	return nil
+}
+func (f *fakeAdb) Restart(app string) error {
+	f.apps = append(f.apps, app)
+	return nil
+}
+
+func newFlow() project.Flow {
+	return project.Flow{
+		Interval: 1,
+		Fallback: []project.Action{{Back: true}, {Restart: "com.example"}},
+		States: []project.State{{
+			Name:  "home",
+			Scene: "home",
+			Transitions: []project.Transition{
+				{If: &project.Compare{Ocr: "home.coin", Op: project.OpGe, Value: "100"}, Do: []project.Action{{Press: "home.buy"}}},
+				{Do: []project.Action{{Press: "home.start"}}},
+			},
+		}, {
+			Name: "done",
+			When: []project.Condition{{Element: "done"}},
+			End:  true,
+		}},
+	}
+}
+
+func newRunner(t *testing.T, img *fakeImg, adb api.ApiAdb) *engine.FlowRunner {
+	r, err := engine.NewFlowRunner(newFlow(), adb, img,
+		map[string]project.ElImg{"home.start": {Offset: image.Pt(5, 5)}},
+		map[string]project.ElArea{"home.coin": {P1: image.Pt(0, 0), P2: image.Pt(10, 10)}},
+		map[string]project.ElPoint{"home.buy": {Point: image.Pt(1, 2)}},
+		map[string]project.ElColor{"done": {}},
+		[]project.Scene{{Name: "home"}},
+		nil,
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return r
+}
+
+func TestFlowRunner(t *testing.T) {
+	points := map[string]image.Point{"home.buy": image.Pt(1, 2), "home.start": image.Pt(15, 15)}
+	img := &fakeImg{scenes: []string{"home", "home", "done"}, ocr: "1,200", points: points}
+	adb := &fakeAdb{}
+	err := newRunner(t, img, adb).Run(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(adb.presses) != 2 || adb.presses[0] != image.Pt(1, 2) {
+		t.Errorf("unexpected presses: %v", adb.presses)
+	}
+
+	// 金币不足时按下 home.start
+	img = &fakeImg{scenes: []string{"home", "done"}, ocr: "99", points: points}
+	adb = &fakeAdb{}
+	err = newRunner(t, img, adb).Run(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(adb.presses) != 1 || adb.presses[0] != image.Pt(15, 15) {
+		t.Errorf("unexpected presses: %v", adb.presses)
+	}
+}
+
+func TestFlowRunnerStuck(t *testing.T) {
+	img := &fakeImg{scenes: []string{"unknown"}}
+	adb := &fakeAdb{}
+	err := newRunner(t, img, adb).Run(context.Background())
+	if !errors.Is(err, engine.ErrFlowStuck) {
+		t.Fatalf("want: %v, got: %v", engine.ErrFlowStuck, err)
+	}
+	// 卡住时执行一次 Fallback
+	if len(adb.keys) != 1 || adb.keys[0] != devices.KeyBack {
+		t.Errorf("unexpected keys: %v", adb.keys)
+	}
+	if len(adb.apps) != 1 || adb.apps[0] != "com.example" {
+		t.Errorf("unexpected restarts: %v", adb.apps)
+	}
+}
+
+func TestFlowRunnerNotFound(t *testing.T) {
+	// home.start 没有找到时不会失败，而是在卡住后执行 Fallback
+	img := &fakeImg{scenes: []string{"home"}, ocr: "99"}
+	adb := &fakeAdb{}
+	err := newRunner(t, img, adb).Run(context.Background())
+	if !errors.Is(err, engine.ErrFlowStuck) {
+		t.Fatalf("want: %v, got: %v", engine.ErrFlowStuck, err)
+	}
+	if len(adb.presses) != 0 || len(adb.keys) != 1 {
+		t.Errorf("unexpected presses: %v, keys: %v", adb.presses, adb.keys)
+	}
+}
+
+func TestNewFlowRunner(t *testing.T) {
+	newRunner := func(f project.Flow) error {
+		_, err := engine.NewFlowRunner(f, &fakeAdb{}, &fakeImg{},
+			map[string]project.ElImg{"home.start": {}},
+			map[string]project.ElArea{"home.coin": {}},
+			map[string]project.ElPoint{"home.buy": {}},
+			map[string]project.ElColor{"done": {}},
+			[]project.Scene{{Name: "home"}},
+			nil,
+		)
+		return err
+	}
+	err := newRunner(newFlow())
+	if err != nil {
+		t.Error("case [good] failed:", err)
+	}
+	bads := map[string]func(f *project.Flow){
+		"undefined scene": func(f *project.Flow) { f.States[0].Scene = "shop" },
+		"undefined when":  func(f *project.Flow) { f.States[1].When[0].Element = "dnoe" },
+		"point when":      func(f *project.Flow) { f.States[1].When[0].Element = "home.buy" },
+		"area when":       func(f *project.Flow) { f.States[1].When[0].Element = "home.coin" },
+		"undefined press": func(f *project.Flow) { f.States[0].Transitions[1].Do[0].Press = "home.stat" },
+	}
+	for k, bad := range bads {
+		f := newFlow()
+		bad(&f)
+		if newRunner(f) == nil {
+			t.Error("case [" + k + "] should be an error, but not")
+		}
+	}
+}
+
+func TestFlowRunnerReconnect(t *testing.T) {
+	points := map[string]image.Point{"home.start": image.Pt(15, 15)}
+	img := &fakeImg{scenes: []string{"home", "home", "done"}, ocr: "99", points: points}
+	adb := &fakeAdb{drop: 1}
+	r := newRunner(t, img, adb)
+	// 没有设置重连函数时断开连接会失败
+	err := r.Run(context.Background())
+	var adbErr *api.AdbError
+	if !errors.As(err, &adbErr) {
+		t.Fatalf("want: api.AdbError, got: %v", err)
+	}
+
+	img = &fakeImg{scenes: []string{"home", "home", "done"}, ocr: "99", points: points}
+	adb = &fakeAdb{drop: 1}
+	r = newRunner(t, img, adb)
+	reconnects := 0
+	r.OnDisconnect(func(ctx context.Context) error {
+		reconnects++
+		return nil
+	})
+	err = r.Run(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if reconnects != 1 || len(adb.presses) != 1 {
+		t.Errorf("unexpected reconnects: %d, presses: %v", reconnects, adb.presses)
+	}
+}
+
+func TestFlowRunnerFakeDevice(t *testing.T) {
+	d, err := devices.NewFakeDevice(devices.FakeConfig{Start: "home"}, map[string][]byte{"home": nil})
+	if err != nil {
+		t.Fatal(err)
+	}
+	img := &fakeImg{scenes: []string{"unknown"}}
+	err = newRunner(t, img, api.NewApiAdb(d)).Run(context.Background())
+	if !errors.Is(err, engine.ErrFlowStuck) {
+		t.Fatalf("want: %v, got: %v", engine.ErrFlowStuck, err)
+	}
+	es := d.Events()
+	if len(es) != 2 || es[0].Type != devices.FakeKey || es[0].Key != devices.KeyBack ||
+		es[1].Type != devices.FakeRestart || es[1].App != "com.example" {
+		t.Errorf("unexpected events: %+v", es)
+	}
+}
@@ -0,0 +1,203 @@
+package lint
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"image"
+	"io/fs"
+	"path"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/HumXC/give-me-time/engine/project"
+	"gocv.io/x/gocv"
+)
+
+// 检查出的一个问题，Element 是元素的路径，File 是相关的文件，可以为空
+type Problem struct {
+	Element string
+	File    string
+	Msg     string
+}
+
+func (p Problem) String() string {
+	s := ""
+	if p.Element != "" {
+		s += "[" + p.Element + "] "
+	}
+	if p.File != "" {
+		s += p.File + ": "
+	}
+	return s + p.Msg
+}
+
+// 会被检查选项和元素引用的脚本文件
+var scriptExts = map[string]struct{}{
+	".lua": {}, ".go": {}, ".py": {}, ".js": {}, ".ts": {},
+}
+
+// Lua 脚本中对元素的引用，例如 FindE("main.start") 或者 click(E.main.start)
+var (
+	luaElementRef   = regexp.MustCompile(`\b\w+E\s*\(\s*["']([^"']+)["']`)
+	luaElementTable = regexp.MustCompile(`\bE((?:\.\w+)+)`)
+)
+
+// 检查一个已经加载的工程，返回所有发现的问题：
+//   - Img 引用的图片不存在或者无法被 gocv.IMDecode 解码
+//   - 内容相同的模板图片
+//   - Threshold 不在 0 - 1 之间
+//   - x2 < x1 或者 y2 < y1 的 area
+//   - 超出 Info.Resolution 的坐标，Relative 为 true 的元素不检查
+//   - 没有在脚本中使用的选项
+//   - Lua 脚本中引用了未定义的元素
+func Lint(p *project.Project) []Problem {
+	ps := make([]Problem, 0)
+	m := make(map[string]project.Element)
+	project.FlatElement(m, "", p.Element)
+	names := make([]string, 0, len(m))
+	for k := range m {
+		names = append(names, k)
+	}
+	sort.Strings(names)
+
+	// 内容的 sha256 对应第一次出现的元素和文件
+	templates := make(map[[32]byte]Problem)
+	for _, k := range names {
+		e := m[k]
+		if e.Type == project.ElTypeImg {
+			imgs := make([]string, 0, len(e.Variant)+1)
+			if e.Img != "" {
+				imgs = append(imgs, e.Img)
+			}
+			for _, v := range e.Variant {
+				imgs = append(imgs, v.Img)
+				ps = append(ps, threshold(k, v.Threshold)...)
+			}
+			for _, img := range imgs {
+				ps = append(ps, lintImg(p.FS, k, img, templates)...)
+			}
+			ps = append(ps, threshold(k, e.Threshold)...)
+		}
+		if e.Type == project.ElTypeArea && (e.Area.X2 < e.Area.X1 || e.Area.Y2 < e.Area.Y1) {
+			ps = append(ps, Problem{Element: k, Msg: fmt.Sprintf("area has x2 < x1 or y2 < y1: %+v", e.Area)})
+		}
+		if !e.Relative {
+			ps = append(ps, bounds(k, e, p.Info.Resolution.Point())...)
+		}
+	}
+
+	scripts := make(map[string]string)
+	_ = fs.WalkDir(p.FS, ".", func(f string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+		if _, ok := scriptExts[path.Ext(f)]; !ok {
+			return nil
+		}
+		b, err := fs.ReadFile(p.FS, f)
+		if err != nil {
+			return err
+		}
+		scripts[f] = string(b)
+		return nil
+	})
+	files := make([]string, 0, len(scripts))
+	for f := range scripts {
+		files = append(files, f)
+	}
+	sort.Strings(files)
+	for _, opt := range p.Option {
+		used := false
+		for _, s := range scripts {
+			if strings.Contains(s, `"`+opt.Name+`"`) || strings.Contains(s, `'`+opt.Name+`'`) {
+				used = true
+				break
+			}
+		}
+		if !used {
+			ps = append(ps, Problem{Msg: fmt.Sprintf("option [%s] is not used in any script", opt.Name)})
+		}
+	}
+	for _, f := range files {
+		if path.Ext(f) != ".lua" {
+			continue
+		}
+		refs := make([]string, 0)
+		for _, ref := range luaElementRef.FindAllStringSubmatch(scripts[f], -1) {
+			refs = append(refs, ref[1])
+		}
+		for _, ref := range luaElementTable.FindAllStringSubmatch(scripts[f], -1) {
+			refs = append(refs, strings.TrimPrefix(ref[1], "."))
+		}
+		for _, ref := range refs {
+			if _, ok := m[ref]; !ok {
+				ps = append(ps, Problem{Element: ref, File: f, Msg: "element is not defined"})
+			}
+		}
+	}
+	return ps
+}
+
+func threshold(e string, t float32) []Problem {
+	if t < 0 || t > 1 {
+		return []Problem{{Element: e, Msg: fmt.Sprintf("threshold [%v] is not in 0 - 1", t)}}
+	}
+	return nil
+}
+
+func lintImg(fsys fs.FS, e, img string, templates map[[32]byte]Problem) []Problem {
+	files, err := fs.Glob(fsys, img)
+	if err != nil || len(files) == 0 {
+		return []Problem{{Element: e, File: img, Msg: "image does not exist"}}
+	}
+	ps := make([]Problem, 0)
+	for _, f := range files {
+		b, err := fs.ReadFile(fsys, f)
+		if err != nil {
+			ps = append(ps, Problem{Element: e, File: f, Msg: err.Error()})
+			continue
+		}
+		mat, err := gocv.IMDecode(b, gocv.IMReadUnchanged)
+		if err != nil || mat.Empty() {
+			ps = append(ps, Problem{Element: e, File: f, Msg: "image can not be decoded"})
+		}
+		if err == nil {
+			mat.Close()
+		}
+		h := sha256.Sum256(b)
+		if first, ok := templates[h]; ok {
+			ps = append(ps, Problem{Element: e, File: f, Msg: fmt.Sprintf("same template as %s of [%s]", first.File, first.Element)})
+			continue
+		}
+		templates[h] = Problem{Element: e, File: f}
+	}
+	return ps
+}
+
+// 检查元素的坐标是否超出了工程的基准分辨率，res 为零值时不检查
+func bounds(k string, e project.Element, res image.Point) []Problem {
+	if res.Eq(image.ZP) {
+		return nil
+	}
+	s := project.Scaler{Ref: res, Screen: res}
+	pts := make([]project.Point, 0)
+	switch e.Type {
+	case project.ElTypeArea:
+		pts = append(pts, project.Point{X: e.Area.X1, Y: e.Area.Y1}, project.Point{X: e.Area.X2, Y: e.Area.Y2})
+	case project.ElTypePoint:
+		pts = append(pts, e.Point)
+	case project.ElTypeColor:
+		for _, c := range e.Color {
+			pts = append(pts, project.Point{X: c.X, Y: c.Y})
+		}
+	}
+	screen := image.Rectangle{Max: res}
+	for _, pt := range pts {
+		p := s.Point(pt, e.Unit, e.Anchor)
+		if p.X < 0 || p.Y < 0 || p.X > screen.Max.X || p.Y > screen.Max.Y {
+			return []Problem{{Element: k, Msg: fmt.Sprintf("point %v is out of the screen %v", p, res)}}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,84 @@
+package lint_test
+
+import (
+	"os"
+	"strings"
+	"testing"
+	"testing/fstest"
+
+	"github.com/HumXC/give-me-time/engine/lint"
+	"github.com/HumXC/give-me-time/engine/project"
+)
+
+func TestLint(t *testing.T) {
+	img, err := os.ReadFile("../project/img_test/button_normal.png")
+	if err != nil {
+		t.Fatal(err)
+	}
+	element := `
+- name: ok
+  img: ../img/a.png
+- name: missing
+  img: ../img/missing.png
+- name: broken
+  img: ../img/broken.png
+- name: zdup
+  img: ../img/b.png
+- name: threshold
+  img: ../img/c.png
+  threshold: 1.5
+- name: area
+  area: {x1: 0, y1: 1, x2: 10, y2: 0}
+- name: out
+  point: {x: 2000, y: 10}
+- name: ratio
+  unit: ratio
+  point: {x: 0.5, y: 0.5}
+  element:
+    - name: rel
+      relative: true
+      point: {x: 3000, y: 0}
+`
+	fsys := fstest.MapFS{
+		"info.yaml":         {Data: []byte("name: test\nruntime:\n    name: lua\n    run: lua main.lua\nresolution:\n    width: 1080\n    height: 2400\n")},
+		"option.json":       {Data: []byte(`[{"name": "times", "type": "number", "default": 1}, {"name": "unused", "type": "bool", "default": false}]`)},
+		"element/main.yaml": {Data: []byte(element)},
+		"img/a.png":         {Data: img},
+		"img/b.png":         {Data: img},
+		"img/c.png":         {Data: append([]byte{}, img[:len(img)-1]...)},
+		"img/broken.png":    {Data: []byte("not a png")},
+		"main.lua":          {Data: []byte(`local n = option("times")` + "\n" + `FindE("ok")` + "\n" + `PointE('ratio.rel')` + "\n" + `PointE("undefined")` + "\n" + `click(E.ratio.rel)` + "\n" + `click(E.tablemissing.x)`)},
+	}
+	p, err := project.Open(fsys)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ps := lint.Lint(p)
+	want := map[string]string{
+		"missing":        "does not exist",
+		"broken":         "can not be decoded",
+		"dup":            "same template",
+		"threshold":      "threshold",
+		"area":           "x2 < x1",
+		"out":            "out of the screen",
+		"undefined":      "not defined",
+		"tablemissing.x": "not defined",
+		"unused":         "not used",
+	}
+	for k, w := range want {
+		found := false
+		for _, p := range ps {
+			if strings.Contains(p.String(), k) && strings.Contains(p.Msg, w) {
+				found = true
+			}
+		}
+		if !found {
+			t.Errorf("want problem [%s] of [%s], got: %v", w, k, ps)
+		}
+	}
+	for _, p := range ps {
+		if p.Element == "ok" || p.Element == "ratio" || p.Element == "ratio.rel" {
+			t.Errorf("unexpected problem: %s", p)
+		}
+	}
+}
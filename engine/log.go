@@ -4,7 +4,7 @@ import (
 	"fmt"
 	"io"
 
-	"github.com/HumXC/adb-helper"
+	"github.com/HumXC/give-me-time/devices"
 	"github.com/HumXC/give-me-time/engine/project"
 	"golang.org/x/exp/slog"
 )
@@ -13,18 +13,26 @@ func LogHandler(o io.Writer) slog.Handler {
 	return slog.NewTextHandler(o)
 }
 
-func LogPrintHead(log io.Writer, projectPath string, dev adb.Device) {
-	fmtMsg := `Project: %s
-Device:
-	ID: %s
-	IsOnline: %t
+// 输出工程的路径和设备的信息，ADB 设备还会输出 devices.Info 中的信息
+func LogPrintHead(log io.Writer, projectPath string, dev devices.Device) {
+	msg := fmt.Sprintf("Project: %s\nDevice:\n\tID: %s\n", projectPath, dev.ID())
+	size, err := dev.ScreenSize()
+	if err == nil {
+		msg += fmt.Sprintf("\tScreenSize: %dx%d\n", size.X, size.Y)
+	}
+	if d, ok := dev.(*devices.ADBDevice); ok {
+		// 查询失败时也会返回已经获取到的部分
+		info, _ := devices.Info(d.Device)
+		fmtMsg := `	IsOnline: %t
 	Product: %s
 	Model: %s
+	AndroidVersion: %s
+	ABI: %s
 	ADBPath: %s
 `
-	msg := fmt.Sprintf(fmtMsg,
-		projectPath,
-		dev.ID, dev.IsOnline, dev.Product, dev.Model, dev.ADBPath)
+		msg += fmt.Sprintf(fmtMsg,
+			info.IsOnline, info.Product, info.Model, info.AndroidVersion, info.ABI, d.Device.ADBPath)
+	}
 	_, _ = io.WriteString(log, msg)
 }
 func LogPrintInfo(log io.Writer, info project.Info) {
@@ -61,3 +69,13 @@ func treeElement(prefix string, es []project.Element) string {
 	}
 	return msg
 }
+
+// 输出选项的值，secret 类型的选项会被隐藏
+func LogPrintOption(log io.Writer, opts []project.Option, values map[string]any) {
+	msg := "Option:\n"
+	masked := project.MaskOption(opts, values)
+	for _, opt := range opts {
+		msg += fmt.Sprintf("	%s: %v\n", opt.Name, masked[opt.Name])
+	}
+	_, _ = io.WriteString(log, msg)
+}
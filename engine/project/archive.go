@@ -0,0 +1,192 @@
+package project
+
+import (
+	"archive/zip"
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// 打包后的工程文件的扩展名，实际上是一个 zip 文件
+const ArchiveExt = ".gmt"
+
+// 工程中的文件名
+const (
+	InfoFile     = "info.yaml"
+	ManifestFile = "manifest.yaml"
+)
+
+// Manifest 记录了打包时工程的信息和每个文件的 sha256，打开工程时用于校验文件
+type Manifest struct {
+	Name    string          `yaml:"name"`
+	Version string          `yaml:"version"`
+	Files   []ManifestEntry `yaml:"files"`
+}
+
+type ManifestEntry struct {
+	Path   string `yaml:"path"`
+	Sha256 string `yaml:"sha256"`
+}
+
+func sha256Hex(b []byte) string {
+	h := sha256.Sum256(b)
+	return hex.EncodeToString(h[:])
+}
+
+// 将 fsys 中的工程打包成 zip 格式写入 w，fsys 的根目录中必须有 info.yaml
+// fsys 中已有的 manifest.yaml 会被忽略并重新生成，用户的 profiles 目录和以 "." 开头的文件不会被打包
+func Pack(fsys fs.FS, w io.Writer) error {
+	makeErr := func(err error) error {
+		return fmt.Errorf("failed to pack project: %w", err)
+	}
+	info, err := LoadInfo(fsys, InfoFile)
+	if err != nil {
+		return makeErr(err)
+	}
+	m := Manifest{Name: info.Name, Version: info.Version}
+	zw := zip.NewWriter(w)
+	err = fs.WalkDir(fsys, ".", func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() && p == ProfileDir {
+			return fs.SkipDir
+		}
+		// 隐藏的文件和目录，例如 .git 和编辑器的配置
+		if p != "." && strings.HasPrefix(d.Name(), ".") {
+			if d.IsDir() {
+				return fs.SkipDir
+			}
+			return nil
+		}
+		if d.IsDir() || p == ManifestFile {
+			return nil
+		}
+		b, err := fs.ReadFile(fsys, p)
+		if err != nil {
+			return err
+		}
+		f, err := zw.Create(p)
+		if err != nil {
+			return err
+		}
+		_, err = f.Write(b)
+		if err != nil {
+			return err
+		}
+		m.Files = append(m.Files, ManifestEntry{Path: p, Sha256: sha256Hex(b)})
+		return nil
+	})
+	if err != nil {
+		return makeErr(err)
+	}
+	mB, err := yaml.Marshal(m)
+	if err != nil {
+		return makeErr(err)
+	}
+	f, err := zw.Create(ManifestFile)
+	if err != nil {
+		return makeErr(err)
+	}
+	_, err = f.Write(mB)
+	if err != nil {
+		return makeErr(err)
+	}
+	err = zw.Close()
+	if err != nil {
+		return makeErr(err)
+	}
+	return nil
+}
+
+// 打开一个打包后的工程，返回的 fs.FS 可以直接用于 LoadInfo 和 LoadElement 等函数
+// 会根据 manifest.yaml 校验所有文件，文件缺失，多余或者 sha256 不一致都会返回错误
+func OpenArchive(r io.ReaderAt, size int64) (fs.FS, *Manifest, error) {
+	makeErr := func(err error) error {
+		return fmt.Errorf("failed to open archive: %w", err)
+	}
+	zr, err := zip.NewReader(r, size)
+	if err != nil {
+		return nil, nil, makeErr(err)
+	}
+	mB, err := fs.ReadFile(zr, ManifestFile)
+	if err != nil {
+		return nil, nil, makeErr(err)
+	}
+	m := new(Manifest)
+	err = yaml.Unmarshal(mB, m)
+	if err != nil {
+		return nil, nil, makeErr(err)
+	}
+	listed := make(map[string]struct{})
+	for _, e := range m.Files {
+		b, err := fs.ReadFile(zr, e.Path)
+		if err != nil {
+			return nil, nil, makeErr(err)
+		}
+		if sha256Hex(b) != e.Sha256 {
+			return nil, nil, makeErr(fmt.Errorf("sha256 of [%s] does not match the manifest", e.Path))
+		}
+		listed[e.Path] = struct{}{}
+	}
+	for _, f := range zr.File {
+		if f.FileInfo().IsDir() || f.Name == ManifestFile {
+			continue
+		}
+		if _, ok := listed[f.Name]; !ok {
+			return nil, nil, makeErr(fmt.Errorf("file [%s] is not in the manifest", f.Name))
+		}
+	}
+	return zr, m, nil
+}
+
+// 打开 file 中的工程，参考 OpenArchive
+func OpenArchiveFile(file string) (fs.FS, *Manifest, error) {
+	b, err := os.ReadFile(file)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to open archive: %w", err)
+	}
+	return OpenArchive(bytes.NewReader(b), int64(len(b)))
+}
+
+// 校验并解压 file 中的工程到 dir 目录
+func Unpack(file string, dir string) error {
+	makeErr := func(err error) error {
+		return fmt.Errorf("failed to unpack project: %w", err)
+	}
+	fsys, m, err := OpenArchiveFile(file)
+	if err != nil {
+		return makeErr(err)
+	}
+	files := []string{ManifestFile}
+	for _, e := range m.Files {
+		files = append(files, e.Path)
+	}
+	sort.Strings(files)
+	for _, f := range files {
+		// zip 中的路径已经经过 fs.ValidPath 的检查，不会出现 ".."
+		b, err := fs.ReadFile(fsys, f)
+		if err != nil {
+			return makeErr(err)
+		}
+		dst := filepath.Join(dir, filepath.FromSlash(f))
+		err = os.MkdirAll(filepath.Dir(dst), 0755)
+		if err != nil {
+			return makeErr(err)
+		}
+		err = os.WriteFile(dst, b, 0644)
+		if err != nil {
+			return makeErr(err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,127 @@
+package project_test
+
+import (
+	"archive/zip"
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+	"testing/fstest"
+
+	"github.com/HumXC/give-me-time/engine/project"
+)
+
+func testProject(t *testing.T) fstest.MapFS {
+	img, err := os.ReadFile("img_test/button_normal.png")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return fstest.MapFS{
+		"info.yaml":           {Data: []byte("name: test\nversion: 1.0.0\nruntime:\n    name: go\n    run: go run .\n")},
+		"element/main.yaml":   {Data: []byte("- name: button\n  img: ../img/button.png\n")},
+		"img/button.png":      {Data: img},
+		"manifest.yaml":       {Data: []byte("name: old\n")},
+		"script/main/main.go": {Data: []byte("package main\n")},
+		"profiles/main.yaml":  {Data: []byte("token: secret\n")},
+		".git/config":         {Data: []byte("[core]\n")},
+		".vscode/launch.json": {Data: []byte("{}\n")},
+		"img/.DS_Store":       {Data: []byte{0}},
+	}
+}
+
+func TestPack(t *testing.T) {
+	buf := new(bytes.Buffer)
+	err := project.Pack(testProject(t), buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	fsys, m, err := project.OpenArchive(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if m.Name != "test" || m.Version != "1.0.0" || len(m.Files) != 4 {
+		t.Fatalf("unexpected manifest: %+v", m)
+	}
+	// 隐藏的文件不会被打包
+	_, err = fsys.Open(".git/config")
+	if err == nil {
+		t.Error("case [.git/config] should not be packed")
+	}
+	// 不解压直接加载
+	es, err := project.LoadElement(fsys, "element/main.yaml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	elImg, _, _, _, err := project.ParseElement(fsys, es, project.Scaler{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(elImg["button"].Img) == 0 {
+		t.Error("img of [button] should not be empty")
+	}
+}
+
+func TestOpenArchive(t *testing.T) {
+	zipFS := func(files map[string]string) *bytes.Reader {
+		buf := new(bytes.Buffer)
+		zw := zip.NewWriter(buf)
+		for k, v := range files {
+			f, err := zw.Create(k)
+			if err != nil {
+				t.Fatal(err)
+			}
+			_, _ = f.Write([]byte(v))
+		}
+		zw.Close()
+		return bytes.NewReader(buf.Bytes())
+	}
+	// sha256 of "a"
+	hash := "ca978112ca1bbdcafac231b39a23dc4da786eff8147c4e72b9807785afee48bb"
+	manifest := "files:\n  - path: a.txt\n    sha256: " + hash + "\n"
+	good := map[string]string{"manifest.yaml": manifest, "a.txt": "a"}
+	bads := map[string]map[string]string{
+		"no manifest": {"a.txt": "a"},
+		"missing":     {"manifest.yaml": manifest},
+		"modified":    {"manifest.yaml": manifest, "a.txt": "b"},
+		"extra":       {"manifest.yaml": manifest, "a.txt": "a", "b.txt": "b"},
+	}
+	r := zipFS(good)
+	_, _, err := project.OpenArchive(r, r.Size())
+	if err != nil {
+		t.Error(err)
+	}
+	for k, v := range bads {
+		r := zipFS(v)
+		_, _, err := project.OpenArchive(r, r.Size())
+		if err == nil {
+			t.Error("case [" + k + "] should be an error, but not")
+		}
+	}
+}
+
+func TestUnpack(t *testing.T) {
+	dir := t.TempDir()
+	file := filepath.Join(dir, "test"+project.ArchiveExt)
+	f, err := os.Create(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = project.Pack(testProject(t), f)
+	f.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+	out := filepath.Join(dir, "out")
+	err = project.Unpack(file, out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = project.LoadInfo(os.DirFS(out), project.InfoFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = os.Stat(filepath.Join(out, "img", "button.png"))
+	if err != nil {
+		t.Error(err)
+	}
+}
@@ -4,8 +4,11 @@ import (
 	_ "embed"
 	"fmt"
 	"image"
-	"os"
+	"image/color"
+	"io/fs"
 	"path"
+	"strconv"
+	"strings"
 
 	"gopkg.in/yaml.v3"
 )
@@ -14,6 +17,31 @@ const (
 	ElTypeImg   = "img"
 	ElTypeArea  = "area"
 	ElTypePoint = "point"
+	ElTypeColor = "color"
+)
+
+// img 元素的匹配方法
+const (
+	MethodTemplate = "template"
+	MethodORB      = "orb"
+	MethodAKAZE    = "akaze"
+)
+
+// 模板匹配的算法
+const (
+	MatchSqdiffNormed = "sqdiff_normed"
+	MatchCcorrNormed  = "ccorr_normed"
+	MatchCcoeffNormed = "ccoeff_normed"
+)
+
+// 模板匹配前对图像的预处理方式
+const (
+	ColorModeColor = "color"
+	ColorModeGray  = "gray"
+	ColorModeHSVH  = "hsv_h"
+	ColorModeHSVS  = "hsv_s"
+	ColorModeHSVV  = "hsv_v"
+	ColorModeCanny = "canny"
 )
 
 // Element 一般用于图像识别
@@ -21,51 +49,123 @@ const (
 // 这 3 者只能有其一发挥作用，优先级为：Img > Area > Point,
 // 也就是说当 Img 不为空时，Area 和 Point 的值不会有作用。
 // Offset 是相对 Img 或者 Area 的偏移量
+// Unit 是 Area, Point, Offset 和 Color 中坐标的单位，Anchor 是坐标的锚点，
+// ParseElement 会根据 Unit 和 Anchor 将坐标转换成设备上的像素
+// Img 可以是一个 glob，例如 "button/*.png"，匹配到的每个文件都是这个元素的一个变体，
+// Variant 用于为变体单独设置 Threshold 和 Offset，查找元素时使用得分最高的变体
+// Relative 为 true 时，Area, Point 和 Color 中的坐标是相对于父元素在屏幕上的位置，
+// 父元素为 img 时是找到的位置，为 area 时是区域的左上角，为 point 时是这个点
+// Method 是 Img 的匹配方法，为空时使用 MethodTemplate
+// Match 是 Method 为 MethodTemplate 时模板匹配的选项
+// Color 是一组点和这些点上应有的颜色，Tolerance 是每个颜色通道允许的误差
 type Element struct {
 	Type        string
-	Name        string      `yaml:"name"`
-	Discription string      `yaml:"discription"`
-	Img         string      `yaml:"img"`
-	Area        Area        `yaml:"area"`
-	Point       image.Point `yaml:"point"`
-	Element     []Element   `yaml:"element"`
-	Offset      image.Point `yaml:"offset"` // 该元素在 Img 或 Area 上的偏移位置
-	Threshold   float32     `yaml:"threshold"`
+	Name        string    `yaml:"name"`
+	Discription string    `yaml:"discription"`
+	Img         string    `yaml:"img"`
+	Variant     []Variant `yaml:"variant"`
+	Area        Area      `yaml:"area"`
+	Point       Point     `yaml:"point"`
+	Element     []Element `yaml:"element"`
+	Offset      Point     `yaml:"offset"` // 该元素在 Img 或 Area 上的偏移位置
+	Threshold   float32   `yaml:"threshold"`
+	Method      string    `yaml:"method"`
+	Match       Match     `yaml:"match"`
+	Color       []Color   `yaml:"color"`
+	Tolerance   int       `yaml:"tolerance"`
+	Unit        string    `yaml:"unit"`
+	Anchor      string    `yaml:"anchor"`
+	Relative    bool      `yaml:"relative"`
 }
+
+// Img, Offset 和 Threshold 与 Variants 中的第一个相同
 type ElImg struct {
 	Discription string
 	Img         []byte
 	Offset      image.Point
 	Threshold   float32
+	Method      string
+	Match       Match
+	Variants    []ElImgVariant
+}
+type ElImgVariant struct {
+	Name      string
+	Img       []byte
+	Offset    image.Point
+	Threshold float32
 }
+
+// img 元素的一个变体，Img 可以是一个 glob
+// Name 为空或者 Img 匹配到多个文件时，使用文件名（不含扩展名）作为变体的名称
+// Threshold 为 0 或者 Offset 为空时使用元素的值
+type Variant struct {
+	Name      string  `yaml:"name"`
+	Img       string  `yaml:"img"`
+	Threshold float32 `yaml:"threshold"`
+	Offset    *Point  `yaml:"offset"`
+}
+
+// Parent 不为空时，坐标是相对于 Parent 这个元素的
 type ElArea struct {
 	Discription string
 	P1, P2      image.Point
+	Parent      string
 }
 type ElPoint struct {
 	image.Point
 	Discription string
+	Parent      string
+}
+type ElColor struct {
+	Discription string
+	Points      []ElColorPoint
+	Tolerance   int
+	Parent      string
+}
+type ElColorPoint struct {
+	image.Point
+	RGB color.RGBA
+}
+
+// 一个点和这个点上的颜色，RGB 的格式为 "#RRGGBB"，"#" 可以省略
+type Color struct {
+	X   float64 `yaml:"x"`
+	Y   float64 `yaml:"y"`
+	RGB string  `yaml:"rgb"`
+}
+
+// 模板匹配的选项，为空的字段会使用默认值
+// Method 默认为 MatchCcoeffNormed，Color 默认为 ColorModeColor
+// Blur 是匹配前高斯模糊的核大小，必须是奇数，为 0 时不进行模糊
+type Match struct {
+	Method string `yaml:"method"`
+	Color  string `yaml:"color"`
+	Blur   int    `yaml:"blur"`
 }
 
 // 从左上角的点坐标到右下角的点坐标
 type Area struct {
-	X1 int `yaml:"x1"`
-	Y1 int `yaml:"y1"`
-	X2 int `yaml:"x2"`
-	Y2 int `yaml:"y2"`
+	X1 float64 `yaml:"x1"`
+	Y1 float64 `yaml:"y1"`
+	X2 float64 `yaml:"x2"`
+	Y2 float64 `yaml:"y2"`
 }
 
 //go:embed element_schame.json
 var elementSchame []byte
 
-// 从 file 加载 json 文件，反序列化成 Element 并验证 Element 的正确性
-// 内部已经调用了 VerifyElement
-func LoadElement(file string) ([]Element, error) {
+// 从 fsys 中的 file 加载 yaml 文件，反序列化成 Element 并验证 Element 的正确性
+// 内部已经调用了 ValidateElement 和 VerifyElement，Img 会被修正为 fsys 中的路径
+func LoadElement(fsys fs.FS, file string) ([]Element, error) {
 	e := make([]Element, 0)
 	makeErr := func(err error) error {
 		return fmt.Errorf("failed to load element: %w", err)
 	}
-	eB, err := os.ReadFile(file)
+	eB, err := fs.ReadFile(fsys, file)
+	if err != nil {
+		return nil, makeErr(err)
+	}
+	err = ValidateElement(eB)
 	if err != nil {
 		return nil, makeErr(err)
 	}
@@ -95,12 +195,14 @@ func SetType(es []Element, ms []map[string]any) []Element {
 	}
 	for i := 0; i < len(es); i++ {
 		switch {
-		case ms[i][ElTypeImg] != nil:
+		case ms[i][ElTypeImg] != nil || ms[i]["variant"] != nil:
 			es[i].Type = ElTypeImg
 		case ms[i][ElTypeArea] != nil:
 			es[i].Type = ElTypeArea
 		case ms[i][ElTypePoint] != nil:
 			es[i].Type = ElTypePoint
+		case ms[i][ElTypeColor] != nil:
+			es[i].Type = ElTypeColor
 		}
 		if es[i].Element != nil {
 			// ms[i]["element"] 无法直接断言成 []map[string]any
@@ -117,9 +219,13 @@ func SetType(es []Element, ms []map[string]any) []Element {
 }
 
 // 检查 Element 中的内容是否符合要求：
-// - Name 不能为空
+// - Name 不能为空，不能包含字符 '.'
 // - 同节点下 Name 不能重复
 // - 如果 Type 不为空，则 Type 必须是已经定义的
+// - 如果 Method 不为空，则 Method 必须是已经定义的
+// - Match 中的 Method 和 Color 必须是已经定义的，Blur 必须是 0 或者正奇数
+// - 如果 Unit 和 Anchor 不为空，则必须是已经定义的
+// - Relative 为 true 的元素必须有类型为 img, area 或 point 的父元素
 func VerifyElement(name string, es []Element) error {
 	if len(es) == 0 {
 		return nil
@@ -132,6 +238,21 @@ func VerifyElement(name string, es []Element) error {
 		if e.Name == "" {
 			return fmt.Errorf("element name is empty in [%s]", name)
 		}
+		if strings.Contains(e.Name, ".") {
+			return fmt.Errorf("element name [%s] can not contain [.] in [%s]", e.Name, name)
+		}
+		if e.Relative && name == "" {
+			return fmt.Errorf("element [%s] is relative but has no parent", e.Name)
+		}
+		for _, sub := range e.Element {
+			if !sub.Relative {
+				continue
+			}
+			if !(e.Type == ElTypeImg || e.Type == ElTypeArea || e.Type == ElTypePoint) {
+				return fmt.Errorf("element [%s] is relative but the type of parent [%s] is [%s]",
+					name+e.Name+"."+sub.Name, name+e.Name, e.Type)
+			}
+		}
 		if _, ok := m[e.Name]; ok {
 			return fmt.Errorf("element name [%s] can not be repeat in [%s]", e.Name, name)
 		}
@@ -140,60 +261,227 @@ func VerifyElement(name string, es []Element) error {
 		case ElTypeImg:
 		case ElTypeArea:
 		case ElTypePoint:
+		case ElTypeColor:
 		default:
 			return fmt.Errorf("element [%s] type [%s] undefined %v ", e.Name, name,
-				[]string{ElTypeImg, ElTypeArea, ElTypePoint})
+				[]string{ElTypeImg, ElTypeArea, ElTypePoint, ElTypeColor})
+		}
+		switch e.Method {
+		case "":
+		case MethodTemplate:
+		case MethodORB:
+		case MethodAKAZE:
+		default:
+			return fmt.Errorf("element [%s] method [%s] undefined %v ", name+e.Name, e.Method,
+				[]string{MethodTemplate, MethodORB, MethodAKAZE})
+		}
+		switch e.Match.Method {
+		case "":
+		case MatchSqdiffNormed:
+		case MatchCcorrNormed:
+		case MatchCcoeffNormed:
+		default:
+			return fmt.Errorf("element [%s] match.method [%s] undefined %v ", name+e.Name, e.Match.Method,
+				[]string{MatchSqdiffNormed, MatchCcorrNormed, MatchCcoeffNormed})
+		}
+		switch e.Match.Color {
+		case "":
+		case ColorModeColor:
+		case ColorModeGray:
+		case ColorModeHSVH:
+		case ColorModeHSVS:
+		case ColorModeHSVV:
+		case ColorModeCanny:
+		default:
+			return fmt.Errorf("element [%s] match.color [%s] undefined %v ", name+e.Name, e.Match.Color,
+				[]string{ColorModeColor, ColorModeGray, ColorModeHSVH, ColorModeHSVS, ColorModeHSVV, ColorModeCanny})
+		}
+		if e.Match.Blur < 0 || (e.Match.Blur != 0 && e.Match.Blur%2 == 0) {
+			return fmt.Errorf("element [%s] match.blur [%d] must be 0 or a positive odd number", name+e.Name, e.Match.Blur)
+		}
+		switch e.Unit {
+		case "":
+		case UnitPx:
+		case UnitRatio:
+		case UnitPercent:
+		default:
+			return fmt.Errorf("element [%s] unit [%s] undefined %v ", name+e.Name, e.Unit,
+				[]string{UnitPx, UnitRatio, UnitPercent})
+		}
+		switch e.Anchor {
+		case "":
+		case AnchorStretch:
+		case AnchorTopLeft:
+		case AnchorTopRight:
+		case AnchorBottomLeft:
+		case AnchorBottomRight:
+		case AnchorCenter:
+		default:
+			return fmt.Errorf("element [%s] anchor [%s] undefined %v ", name+e.Name, e.Anchor,
+				[]string{AnchorStretch, AnchorTopLeft, AnchorTopRight, AnchorBottomLeft, AnchorBottomRight, AnchorCenter})
 		}
 		m[e.Name] = struct{}{}
-		VerifyElement(name+e.Name, e.Element)
+		err := VerifyElement(name+e.Name, e.Element)
+		if err != nil {
+			return err
+		}
 	}
 	return nil
 }
 
-func ParseElement(elements []Element) (map[string]ElImg, map[string]ElArea, map[string]ElPoint, error) {
+// 解析 Element，从 fsys 中读取图片，并使用 s 将元素中的坐标转换成设备上的像素
+// 使用 UnitRatio 或者 UnitPercent 时，s 中的 Ref 和 Screen 至少要有一个不为零值
+func ParseElement(fsys fs.FS, elements []Element, s Scaler) (map[string]ElImg, map[string]ElArea, map[string]ElPoint, map[string]ElColor, error) {
 	elImg := make(map[string]ElImg)
 	elArea := make(map[string]ElArea)
 	elPoint := make(map[string]ElPoint)
+	elColor := make(map[string]ElColor)
 	fElement := make(map[string]Element)
 	storeImg := func(k string, e Element) error {
-		b, err := os.ReadFile(e.Img)
+		method := e.Method
+		if method == "" {
+			method = MethodTemplate
+		}
+		match := e.Match
+		if match.Method == "" {
+			match.Method = MatchCcoeffNormed
+		}
+		if match.Color == "" {
+			match.Color = ColorModeColor
+		}
+		offset := s.Vector(e.Offset, e.Unit, e.Anchor)
+		vs := make([]Variant, 0, len(e.Variant)+1)
+		if e.Img != "" {
+			vs = append(vs, Variant{Img: e.Img})
+		}
+		vs = append(vs, e.Variant...)
+		variants := make([]ElImgVariant, 0, len(vs))
+		for _, v := range vs {
+			files, err := globImg(fsys, v.Img)
+			if err != nil {
+				return err
+			}
+			for _, f := range files {
+				b, err := fs.ReadFile(fsys, f)
+				if err != nil {
+					return err
+				}
+				ev := ElImgVariant{
+					Name:      v.Name,
+					Img:       b,
+					Offset:    offset,
+					Threshold: e.Threshold,
+				}
+				if ev.Name == "" || len(files) > 1 {
+					ev.Name = strings.TrimSuffix(path.Base(f), path.Ext(f))
+				}
+				if v.Threshold != 0 {
+					ev.Threshold = v.Threshold
+				}
+				if v.Offset != nil {
+					ev.Offset = s.Vector(*v.Offset, e.Unit, e.Anchor)
+				}
+				variants = append(variants, ev)
+			}
+		}
+		if len(variants) == 0 {
+			return fmt.Errorf("img element has no image")
+		}
 		elImg[k] = ElImg{
 			Discription: e.Discription,
-			Img:         b,
-			Offset:      e.Offset,
-			Threshold:   e.Threshold,
+			Img:         variants[0].Img,
+			Offset:      variants[0].Offset,
+			Threshold:   variants[0].Threshold,
+			Method:      method,
+			Match:       match,
+			Variants:    variants,
 		}
-		return err
-
+		return nil
+	}
+	// 相对于父元素的坐标是偏移量，不受锚点位置的影响
+	point := func(p Point, e Element) image.Point {
+		if e.Relative {
+			return s.Vector(p, e.Unit, e.Anchor)
+		}
+		return s.Point(p, e.Unit, e.Anchor)
+	}
+	parent := func(k string, e Element) string {
+		if !e.Relative {
+			return ""
+		}
+		return k[:strings.LastIndex(k, ".")]
 	}
 	storeArea := func(k string, e Element) {
 		elArea[k] = ElArea{
 			Discription: e.Discription,
-			P1:          image.Pt(e.Area.X1, e.Area.Y1),
-			P2:          image.Pt(e.Area.X2, e.Area.Y2),
+			P1:          point(Point{X: e.Area.X1, Y: e.Area.Y1}, e),
+			P2:          point(Point{X: e.Area.X2, Y: e.Area.Y2}, e),
+			Parent:      parent(k, e),
 		}
 	}
 	storePoint := func(k string, e Element) {
 		elPoint[k] = ElPoint{
 			Discription: e.Discription,
-			Point:       e.Point,
+			Point:       point(e.Point, e),
+			Parent:      parent(k, e),
 		}
 	}
+	storeColor := func(k string, e Element) error {
+		points := make([]ElColorPoint, 0, len(e.Color))
+		for _, c := range e.Color {
+			rgb, err := ParseColor(c.RGB)
+			if err != nil {
+				return err
+			}
+			points = append(points, ElColorPoint{
+				Point: point(Point{X: c.X, Y: c.Y}, e),
+				RGB:   rgb,
+			})
+		}
+		elColor[k] = ElColor{
+			Discription: e.Discription,
+			Points:      points,
+			Tolerance:   e.Tolerance,
+			Parent:      parent(k, e),
+		}
+		return nil
+	}
 	FlatElement(fElement, "", elements)
 	for k, e := range fElement {
+		if (e.Unit == UnitRatio || e.Unit == UnitPercent) && s.Ref.Eq(image.ZP) && s.Screen.Eq(image.ZP) {
+			return nil, nil, nil, nil, fmt.Errorf("failed to parse element [%s]: unit [%s] requires a resolution", k, e.Unit)
+		}
 		switch e.Type {
 		case ElTypeImg:
 			err := storeImg(k, e)
 			if err != nil {
-				return nil, nil, nil, fmt.Errorf("failed to parse element [%s]: %w", k, err)
+				return nil, nil, nil, nil, fmt.Errorf("failed to parse element [%s]: %w", k, err)
 			}
 		case ElTypeArea:
 			storeArea(k, e)
 		case ElTypePoint:
 			storePoint(k, e)
+		case ElTypeColor:
+			err := storeColor(k, e)
+			if err != nil {
+				return nil, nil, nil, nil, fmt.Errorf("failed to parse element [%s]: %w", k, err)
+			}
 		}
 	}
-	return elImg, elArea, elPoint, nil
+	return elImg, elArea, elPoint, elColor, nil
+}
+
+// 将 "#RRGGBB" 或者 "RRGGBB" 形式的字符串解析成颜色
+func ParseColor(s string) (color.RGBA, error) {
+	hex := strings.TrimPrefix(s, "#")
+	if len(hex) != 6 {
+		return color.RGBA{}, fmt.Errorf("invalid color [%s], want [#RRGGBB]", s)
+	}
+	v, err := strconv.ParseUint(hex, 16, 32)
+	if err != nil {
+		return color.RGBA{}, fmt.Errorf("invalid color [%s], want [#RRGGBB]", s)
+	}
+	return color.RGBA{R: uint8(v >> 16), G: uint8(v >> 8), B: uint8(v), A: 0xff}, nil
 }
 
 // 扁平化 Element 存储到 map 中，Element.Element 将被赋值为 nil 不再嵌套
@@ -214,19 +502,42 @@ func FlatElement(m map[string]Element, name string, es []Element) {
 	}
 }
 
-// 判断路径类型，修正相对路径
+// 将 Img 修正为 fs.FS 中的路径，包括 Variant 中的路径
+// 以 "/" 开头的路径相对于工程的根目录，其他路径相对于 basePath，"\" 也会被当作路径分隔符
 func PatchAbsPath(es []Element, basePath string) {
 	if len(es) == 0 {
 		return
 	}
-	for i := 0; i < len(es); i++ {
-		if es[i].Img == "" {
-			continue
+	patch := func(p string) string {
+		if p == "" {
+			return p
 		}
-		if path.IsAbs(es[i].Img) {
-			continue
+		p = strings.ReplaceAll(p, "\\", "/")
+		if path.IsAbs(p) {
+			return path.Clean(p[1:])
+		}
+		return path.Join(basePath, p)
+	}
+	for i := 0; i < len(es); i++ {
+		es[i].Img = patch(es[i].Img)
+		for j := 0; j < len(es[i].Variant); j++ {
+			es[i].Variant[j].Img = patch(es[i].Variant[j].Img)
 		}
-		es[i].Img = path.Join(basePath, es[i].Img)
 		PatchAbsPath(es[i].Element, basePath)
 	}
 }
+
+// 返回 pattern 匹配到的文件，pattern 不是 glob 时直接返回 pattern
+func globImg(fsys fs.FS, pattern string) ([]string, error) {
+	if !strings.ContainsAny(pattern, "*?[") {
+		return []string{pattern}, nil
+	}
+	files, err := fs.Glob(fsys, pattern)
+	if err != nil {
+		return nil, err
+	}
+	if len(files) == 0 {
+		return nil, fmt.Errorf("no file matches [%s]", pattern)
+	}
+	return files, nil
+}
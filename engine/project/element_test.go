@@ -2,6 +2,10 @@ package project_test
 
 import (
 	_ "embed"
+	"errors"
+	"image"
+	"image/color"
+	"os"
 	"testing"
 
 	"github.com/HumXC/give-me-time/engine/project"
@@ -12,7 +16,7 @@ import (
 var elementTest []byte
 
 func TestLoadElement(t *testing.T) {
-	_, err := project.LoadElement("element_test.yaml")
+	_, err := project.LoadElement(os.DirFS("."), "element_test.yaml")
 	if err != nil {
 		t.Error(err)
 	}
@@ -30,9 +34,8 @@ func TestVerifyElement(t *testing.T) {
 				{Name: "test7"},
 				{Name: "test8"},
 			}},
-		{Name: "test3"},
-		// 有 Name 包含符号 '.'
-		{Name: "test.a"},
+		{Name: "test3", Type: "img", Method: "orb"},
+		{Name: "test9", Type: "img", Match: project.Match{Method: "sqdiff_normed", Color: "canny", Blur: 3}},
 		// 有 Name 包含符号 '-'
 		{Name: "test-a"},
 	}
@@ -62,6 +65,32 @@ func TestVerifyElement(t *testing.T) {
 		"bad4": {
 			{Name: "dd", Type: "imgd"},
 		},
+		// Method 不符合要求
+		"bad5": {
+			{Name: "dd", Type: "img", Method: "sift"},
+		},
+		// 有 Name 包含符号 '.'
+		"bad9": {
+			{Name: "test.a"},
+		},
+		// 嵌套的 Element 中有错误
+		"bad10": {
+			{Name: "test", Element: []project.Element{
+				{Name: "test2", Element: []project.Element{
+					{Name: "dd", Type: "imgd"},
+				}},
+			}},
+		},
+		// Match 不符合要求
+		"bad6": {
+			{Name: "dd", Type: "img", Match: project.Match{Method: "sqdiff"}},
+		},
+		"bad7": {
+			{Name: "dd", Type: "img", Match: project.Match{Color: "rgb"}},
+		},
+		"bad8": {
+			{Name: "dd", Type: "img", Match: project.Match{Blur: 4}},
+		},
 	}
 
 	err := project.VerifyElement("", good1)
@@ -100,6 +129,7 @@ func TestSetType(t *testing.T) {
 		"main.start":      "",
 		"main.text":       "area",
 		"main.text.input": "point",
+		"game.dot":        "color",
 	}
 	es = project.SetType(es, ms)
 
@@ -112,3 +142,202 @@ func TestSetType(t *testing.T) {
 		}
 	}
 }
+
+func TestParseColor(t *testing.T) {
+	goods := map[string]color.RGBA{
+		"#FF0000": {R: 0xff, A: 0xff},
+		"00ff7f":  {G: 0xff, B: 0x7f, A: 0xff},
+		"#123456": {R: 0x12, G: 0x34, B: 0x56, A: 0xff},
+	}
+	for s, want := range goods {
+		got, err := project.ParseColor(s)
+		if err != nil {
+			t.Errorf("case [%s]: %v", s, err)
+			continue
+		}
+		if got != want {
+			t.Errorf("case [%s] want: %v, got: %v", s, want, got)
+		}
+	}
+	bads := []string{"", "#", "#FFF", "#GG0000", "#FF00001", "##FF0000"}
+	for _, s := range bads {
+		_, err := project.ParseColor(s)
+		if err == nil {
+			t.Errorf("case [%s] should be an error, but not", s)
+		}
+	}
+}
+
+func TestParseElementColor(t *testing.T) {
+	es, err := project.LoadElement(os.DirFS("."), "element_test.yaml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// main 的 img 不存在，只解析 game
+	_, _, _, elColor, err := project.ParseElement(os.DirFS("."), es[1:], project.Scaler{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	dot, ok := elColor["game.dot"]
+	if !ok {
+		t.Fatal("color element [game.dot] not found")
+	}
+	if dot.Tolerance != 16 || len(dot.Points) != 2 {
+		t.Fatalf("unexpected color element: %+v", dot)
+	}
+	want := project.ElColorPoint{Point: image.Pt(11, 20), RGB: color.RGBA{R: 0xff, A: 0xff}}
+	if dot.Points[1] != want {
+		t.Errorf("want: %v, got: %v", want, dot.Points[1])
+	}
+}
+
+func TestValidateElement(t *testing.T) {
+	err := project.ValidateElement(elementTest)
+	if err != nil {
+		t.Fatal(err)
+	}
+	bad := []byte(`- name: a.b
+  img: 1
+  area:
+      x1: 1
+      y1: 1
+      x2: 1
+  element:
+      - name: c
+        unknown: 1
+        method: sift
+        tolerance: 300
+- discription: no name
+`)
+	err = project.ValidateElement(bad)
+	var errs project.SchemaErrors
+	if !errors.As(err, &errs) {
+		t.Fatalf("want: SchemaErrors, got: %v", err)
+	}
+	want := []project.SchemaError{
+		{Line: 1, Column: 9, Path: "$[0].name"},
+		{Line: 2, Column: 8, Path: "$[0].img"},
+		{Line: 4, Column: 7, Path: "$[0].area"},
+		{Line: 9, Column: 9, Path: "$[0].element[0].unknown"},
+		{Line: 10, Column: 17, Path: "$[0].element[0].method"},
+		{Line: 11, Column: 20, Path: "$[0].element[0].tolerance"},
+		{Line: 12, Column: 3, Path: "$[1]"},
+	}
+	if len(errs) != len(want) {
+		t.Fatalf("want: %d errors, got: %d\n%v", len(want), len(errs), errs)
+	}
+	for i, w := range want {
+		e := errs[i]
+		if e.Line != w.Line || e.Column != w.Column || e.Path != w.Path {
+			t.Errorf("want: %d:%d %s, got: %d:%d %s", w.Line, w.Column, w.Path, e.Line, e.Column, e.Path)
+		}
+	}
+}
+
+func TestParseElementUnit(t *testing.T) {
+	es := []project.Element{{
+		Name:  "a",
+		Type:  project.ElTypePoint,
+		Unit:  project.UnitRatio,
+		Point: project.Point{X: 0.5, Y: 0.5},
+	}}
+	// 没有分辨率时无法转换 ratio
+	_, _, _, _, err := project.ParseElement(os.DirFS("."), es, project.Scaler{})
+	if err == nil {
+		t.Error("unit [ratio] without resolution should be an error, but not")
+	}
+	_, _, elPoint, _, err := project.ParseElement(os.DirFS("."), es, project.Scaler{Screen: image.Pt(720, 1280)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := image.Pt(360, 640); !elPoint["a"].Point.Eq(want) {
+		t.Errorf("want: %v, got: %v", want, elPoint["a"].Point)
+	}
+}
+
+func TestParseElementRelative(t *testing.T) {
+	es := []project.Element{{
+		Name: "panel",
+		Type: project.ElTypeArea,
+		Area: project.Area{X1: 100, Y1: 100, X2: 500, Y2: 500},
+		Element: []project.Element{{
+			Name:     "buy",
+			Type:     project.ElTypePoint,
+			Point:    project.Point{X: 10, Y: 20},
+			Relative: true,
+			// 相对坐标不受锚点位置的影响
+			Anchor: project.AnchorBottomRight,
+		}, {
+			Name:  "close",
+			Type:  project.ElTypePoint,
+			Point: project.Point{X: 10, Y: 20},
+		}},
+	}}
+	err := project.VerifyElement("", es)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, _, elPoint, _, err := project.ParseElement(os.DirFS("."), es, project.Scaler{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	buy := elPoint["panel.buy"]
+	if buy.Parent != "panel" || !buy.Point.Eq(image.Pt(10, 20)) {
+		t.Errorf("unexpected element [panel.buy]: %+v", buy)
+	}
+	if c := elPoint["panel.close"]; c.Parent != "" {
+		t.Errorf("element [panel.close] should not have parent, got: [%s]", c.Parent)
+	}
+
+	bads := map[string][]project.Element{
+		// 没有父元素
+		"bad1": {{Name: "a", Relative: true}},
+		// 父元素没有位置
+		"bad2": {{Name: "a", Element: []project.Element{{Name: "b", Relative: true}}}},
+		"bad3": {{Name: "a", Type: project.ElTypeColor, Element: []project.Element{{Name: "b", Relative: true}}}},
+	}
+	for k, v := range bads {
+		err = project.VerifyElement("", v)
+		if err == nil {
+			t.Error("case [" + k + "] should be an error, but not")
+		}
+	}
+}
+
+func TestParseElementVariant(t *testing.T) {
+	es, err := project.LoadElement(os.DirFS("."), "variant_test.yaml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	elImg, _, _, _, err := project.ParseElement(os.DirFS("."), es, project.Scaler{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	button := elImg["button"]
+	if len(button.Variants) != 2 {
+		t.Fatalf("want: 2 variants, got: %d", len(button.Variants))
+	}
+	want := []struct {
+		name      string
+		offset    image.Point
+		threshold float32
+	}{
+		{"button_normal", image.Pt(5, 5), 0.8},
+		{"event", image.Pt(10, 10), 0.6},
+	}
+	for i, w := range want {
+		v := button.Variants[i]
+		if v.Name != w.name || !v.Offset.Eq(w.offset) || v.Threshold != w.threshold || len(v.Img) == 0 {
+			t.Errorf("variant [%d] want: %v, got: {%s %v %v}", i, w, v.Name, v.Offset, v.Threshold)
+		}
+	}
+	// glob 匹配到的每个文件都是一个变体
+	all := elImg["all"]
+	names := make([]string, 0)
+	for _, v := range all.Variants {
+		names = append(names, v.Name)
+	}
+	if len(names) != 3 || names[0] != "button_event" || names[2] != "button_normal" {
+		t.Errorf("unexpected variants: %v", names)
+	}
+}
@@ -0,0 +1,269 @@
+package project
+
+import (
+	"fmt"
+	"io/fs"
+	"regexp"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Flow 用状态机描述一个不需要写代码的任务
+// 每一步都会根据当前屏幕判断处于哪个 State，然后执行这个 State 的 Transition，
+// 直到进入 End 为 true 的 State
+// Retry 和 Fallback 用于无法判断当前 State 的情况，Retry 同时也是 State 的 Retry 的默认值
+type Flow struct {
+	Name     string   `yaml:"name"`
+	MaxSteps int      `yaml:"max_steps"` // 最多执行的步数，为 0 时使用 DefaultFlowMaxSteps
+	Interval int      `yaml:"interval"`  // 每一步之间的间隔，单位是 ms，为 0 时使用 DefaultFlowInterval
+	Retry    int      `yaml:"retry"`
+	Fallback []Action `yaml:"fallback"`
+	States   []State  `yaml:"states"`
+}
+
+// State 是 Flow 中的一个状态，使用 Scene 或者 When 判断当前是否处于这个状态
+// When 中的条件需要全部满足
+// 同一个 State 连续出现超过 Retry 次时会执行一次 Fallback，如果 Fallback 之后依然没有变化则 Flow 失败
+// State 的 Fallback 为空时使用 Flow 的 Fallback
+type State struct {
+	Name        string       `yaml:"name"`
+	Discription string       `yaml:"discription"`
+	Scene       string       `yaml:"scene"`
+	When        []Condition  `yaml:"when"`
+	End         bool         `yaml:"end"`
+	Retry       int          `yaml:"retry"`
+	Transitions []Transition `yaml:"transitions"`
+	Fallback    []Action     `yaml:"fallback"`
+}
+
+// Transition 在 If 满足时执行 Do，If 为空时总是满足
+// 一个 State 中只会执行第一个满足条件的 Transition
+type Transition struct {
+	If *Compare `yaml:"if"`
+	Do []Action `yaml:"do"`
+}
+
+// 将 area 元素 Ocr 的文字识别结果与 Value 比较
+// Op 为 eq, ne, contains 时按字符串比较，为 lt, le, gt, ge 时按数字比较
+type Compare struct {
+	Ocr   string `yaml:"ocr"`
+	Op    string `yaml:"op"`
+	Value string `yaml:"value"`
+}
+
+// Action 是一个操作，每个 Action 只能设置其中一个字段
+// - Press：按下一个元素，img 元素按下找到的位置，area 元素按下区域的中心
+// - Swipe：滑动
+// - Wait：等待，单位是 ms
+// - Back：按下返回键
+// - Restart：重启一个应用，值为应用的包名
+type Action struct {
+	Press   string `yaml:"press"`
+	Swipe   *Swipe `yaml:"swipe"`
+	Wait    int    `yaml:"wait"`
+	Back    bool   `yaml:"back"`
+	Restart string `yaml:"restart"`
+}
+
+type Swipe struct {
+	X1       int `yaml:"x1"`
+	Y1       int `yaml:"y1"`
+	X2       int `yaml:"x2"`
+	Y2       int `yaml:"y2"`
+	Duration int `yaml:"duration"`
+}
+
+const (
+	DefaultFlowMaxSteps = 100
+	DefaultFlowInterval = 500
+	DefaultFlowRetry    = 3
+)
+
+const (
+	OpEq       = "eq"
+	OpNe       = "ne"
+	OpContains = "contains"
+	OpLt       = "lt"
+	OpLe       = "le"
+	OpGt       = "gt"
+	OpGe       = "ge"
+)
+
+// 从 fsys 中的 file 加载 yaml 文件，反序列化成 Flow 并验证 Flow 的正确性
+// 内部已经调用了 VerifyFlow 和 SetFlowDefault
+func LoadFlow(fsys fs.FS, file string) (*Flow, error) {
+	f := new(Flow)
+	makeErr := func(err error) error {
+		return fmt.Errorf("failed to load flow: %w", err)
+	}
+	fB, err := fs.ReadFile(fsys, file)
+	if err != nil {
+		return nil, makeErr(err)
+	}
+	err = yaml.Unmarshal(fB, f)
+	if err != nil {
+		return nil, makeErr(err)
+	}
+	err = VerifyFlow(*f)
+	if err != nil {
+		return nil, makeErr(err)
+	}
+	SetFlowDefault(f)
+	return f, nil
+}
+
+// 为 Flow 中值为 0 的 MaxSteps，Interval 和 Retry 赋予默认值
+// State 的 Retry 为 0 时使用 Flow 的 Retry
+func SetFlowDefault(f *Flow) {
+	if f.MaxSteps == 0 {
+		f.MaxSteps = DefaultFlowMaxSteps
+	}
+	if f.Interval == 0 {
+		f.Interval = DefaultFlowInterval
+	}
+	if f.Retry == 0 {
+		f.Retry = DefaultFlowRetry
+	}
+	for i := 0; i < len(f.States); i++ {
+		if f.States[i].Retry == 0 {
+			f.States[i].Retry = f.Retry
+		}
+	}
+}
+
+// 检查 Flow 中的内容是否符合要求：
+// - States 不能为空，State 的 Name 不能为空且不能重复
+// - State 必须设置 Scene 或者 When
+// - End 不为 true 的 State 必须有 Transition
+// - Compare 的 Ocr 和 Op 不能为空，Op 必须是已经定义的
+// - Action 必须设置且只能设置一个字段
+// - MaxSteps，Interval 和 Retry 不能小于 0
+func VerifyFlow(f Flow) error {
+	if len(f.States) == 0 {
+		return fmt.Errorf("flow has no [states]")
+	}
+	if f.MaxSteps < 0 || f.Interval < 0 || f.Retry < 0 {
+		return fmt.Errorf("[max_steps], [interval] and [retry] can not be negative")
+	}
+	err := verifyActions("fallback", f.Fallback)
+	if err != nil {
+		return err
+	}
+	m := make(map[string]struct{})
+	for _, s := range f.States {
+		if s.Name == "" {
+			return fmt.Errorf("state name is empty")
+		}
+		if _, ok := m[s.Name]; ok {
+			return fmt.Errorf("state name [%s] can not be repeat", s.Name)
+		}
+		m[s.Name] = struct{}{}
+		if s.Scene == "" && len(s.When) == 0 {
+			return fmt.Errorf("state [%s] must have [scene] or [when]", s.Name)
+		}
+		for _, c := range s.When {
+			if c.Element == "" {
+				return fmt.Errorf("state [%s] has a condition without [element]", s.Name)
+			}
+		}
+		if s.Retry < 0 {
+			return fmt.Errorf("state [%s] [retry] can not be negative", s.Name)
+		}
+		if !s.End && len(s.Transitions) == 0 {
+			return fmt.Errorf("state [%s] must have [transitions] or be [end]", s.Name)
+		}
+		for i, t := range s.Transitions {
+			where := fmt.Sprintf("state [%s] transition [%d]", s.Name, i)
+			if t.If != nil {
+				err := verifyCompare(*t.If)
+				if err != nil {
+					return fmt.Errorf("%s: %w", where, err)
+				}
+			}
+			err := verifyActions(where, t.Do)
+			if err != nil {
+				return err
+			}
+		}
+		err := verifyActions(fmt.Sprintf("state [%s] fallback", s.Name), s.Fallback)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// 检查 Flow 中 State 的 When 与元素是否对应：
+// - 元素必须已经定义，并且是 img，color 或者 area 元素
+// - area 元素的条件必须有 Text，否则任何文字识别结果都满足条件
+func VerifyFlowElement(flows []Flow, es []Element) error {
+	m := make(map[string]Element)
+	FlatElement(m, "", es)
+	for _, f := range flows {
+		for _, s := range f.States {
+			for _, c := range s.When {
+				e, ok := m[c.Element]
+				if !ok {
+					return fmt.Errorf("flow [%s] state [%s]: element [%s] undefiend", f.Name, s.Name, c.Element)
+				}
+				switch e.Type {
+				case ElTypeImg, ElTypeColor:
+				case ElTypeArea:
+					if c.Text == "" {
+						return fmt.Errorf("flow [%s] state [%s]: condition of area element [%s] must have [text]", f.Name, s.Name, c.Element)
+					}
+				default:
+					return fmt.Errorf("flow [%s] state [%s]: element [%s] is not an img, color or area element", f.Name, s.Name, c.Element)
+				}
+			}
+		}
+	}
+	return nil
+}
+
+func verifyCompare(c Compare) error {
+	if c.Ocr == "" {
+		return fmt.Errorf("field [ocr] cannot be empty in compare")
+	}
+	switch c.Op {
+	case OpEq, OpNe, OpContains, OpLt, OpLe, OpGt, OpGe:
+	default:
+		return fmt.Errorf("compare op [%s] undefined %v", c.Op,
+			[]string{OpEq, OpNe, OpContains, OpLt, OpLe, OpGt, OpGe})
+	}
+	return nil
+}
+
+// Android 的包名，例如 "com.example.game"
+var packageName = regexp.MustCompile(`^[A-Za-z][A-Za-z0-9_]*(\.[A-Za-z0-9_]+)*$`)
+
+func verifyActions(where string, as []Action) error {
+	for i, a := range as {
+		n := 0
+		if a.Press != "" {
+			n++
+		}
+		if a.Swipe != nil {
+			n++
+		}
+		if a.Wait != 0 {
+			n++
+		}
+		if a.Back {
+			n++
+		}
+		if a.Restart != "" {
+			n++
+		}
+		if n != 1 {
+			return fmt.Errorf("%s action [%d] must have exactly one of [press|swipe|wait|back|restart]", where, i)
+		}
+		if a.Wait < 0 {
+			return fmt.Errorf("%s action [%d] [wait] can not be negative", where, i)
+		}
+		if a.Restart != "" && !packageName.MatchString(a.Restart) {
+			return fmt.Errorf("%s action [%d] [restart] [%s] is not a package name", where, i, a.Restart)
+		}
+	}
+	return nil
+}
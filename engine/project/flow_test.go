@@ -0,0 +1,102 @@
+package project_test
+
+import (
+	"os"
+	"testing"
+
+	"github.com/HumXC/give-me-time/engine/project"
+)
+
+func TestLoadFlow(t *testing.T) {
+	f, err := project.LoadFlow(os.DirFS("."), "flow_test.yaml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if f.MaxSteps != project.DefaultFlowMaxSteps || f.Interval != project.DefaultFlowInterval {
+		t.Errorf("default value not set: %+v", f)
+	}
+	// 没有设置 retry 的 State 使用 Flow 的 retry
+	want := []int{2, 5, 2}
+	for i, s := range f.States {
+		if s.Retry != want[i] {
+			t.Errorf("state [%s] retry want: %d, got: %d", s.Name, want[i], s.Retry)
+		}
+	}
+	if f.States[1].Transitions[1].Do[0].Swipe.Y1 != 500 {
+		t.Errorf("swipe not loaded: %+v", f.States[1].Transitions[1].Do[0])
+	}
+}
+
+func TestVerifyFlow(t *testing.T) {
+	home := project.State{
+		Name:        "home",
+		Scene:       "home",
+		Transitions: []project.Transition{{Do: []project.Action{{Press: "main.start"}}}},
+	}
+	good := project.Flow{
+		States:   []project.State{home, {Name: "end", Scene: "end", End: true}},
+		Fallback: []project.Action{{Restart: "com.example.game_2"}},
+	}
+	err := project.VerifyFlow(good)
+	if err != nil {
+		t.Error("case [good] verify failed:", err)
+	}
+	bads := map[string]project.Flow{
+		// 没有 State
+		"bad1": {},
+		// Name 重复
+		"bad2": {States: []project.State{home, home}},
+		// 没有 Scene 和 When
+		"bad3": {States: []project.State{{Name: "a", End: true}}},
+		// 没有 Transition
+		"bad4": {States: []project.State{{Name: "a", Scene: "a"}}},
+		// Action 设置了多个字段
+		"bad5": {States: []project.State{{Name: "a", Scene: "a", Transitions: []project.Transition{
+			{Do: []project.Action{{Press: "main", Back: true}}},
+		}}}},
+		// Action 为空
+		"bad6": {States: []project.State{home}, Fallback: []project.Action{{}}},
+		// Op 不符合要求
+		"bad7": {States: []project.State{{Name: "a", Scene: "a", Transitions: []project.Transition{
+			{If: &project.Compare{Ocr: "main", Op: "=="}},
+		}}}},
+		// 负数
+		"bad8": {States: []project.State{home}, Retry: -1},
+		// restart 不是包名
+		"bad9": {States: []project.State{home}, Fallback: []project.Action{{Restart: "com.example; rm -rf /"}}},
+	}
+	for k, v := range bads {
+		err = project.VerifyFlow(v)
+		if err == nil {
+			t.Error("case [" + k + "] should be an error, but not")
+		}
+	}
+}
+
+func TestVerifyFlowElement(t *testing.T) {
+	es := []project.Element{{Name: "main", Element: []project.Element{
+		{Name: "coin", Type: project.ElTypeArea},
+		{Name: "start", Type: project.ElTypeImg},
+		{Name: "red", Type: project.ElTypeColor},
+		{Name: "center", Type: project.ElTypePoint},
+	}}}
+	flow := func(cs ...project.Condition) []project.Flow {
+		return []project.Flow{{Name: "f", States: []project.State{{Name: "a", When: cs, End: true}}}}
+	}
+	good := flow(project.Condition{Element: "main.coin", Text: "金币"}, project.Condition{Element: "main.start"}, project.Condition{Element: "main.red"})
+	err := project.VerifyFlowElement(good, es)
+	if err != nil {
+		t.Error("case [good] verify failed:", err)
+	}
+	bads := map[string][]project.Flow{
+		"undefined": flow(project.Condition{Element: "main.strat"}),
+		"no text":   flow(project.Condition{Element: "main.coin"}),
+		"point":     flow(project.Condition{Element: "main.center"}),
+	}
+	for k, v := range bads {
+		err = project.VerifyFlowElement(v, es)
+		if err == nil {
+			t.Error("case [" + k + "] should be an error, but not")
+		}
+	}
+}
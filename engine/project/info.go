@@ -2,17 +2,38 @@ package project
 
 import (
 	"fmt"
-	"os"
+	"image"
+	"io/fs"
 	"runtime"
 
 	"gopkg.in/yaml.v3"
 )
 
 type Info struct {
-	Name        string  `yaml:"name"`
-	Discription string  `yaml:"discription"`
-	Version     string  `yaml:"version"`
-	Runtime     Runtime `yaml:"runtime"`
+	Name        string     `yaml:"name"`
+	Discription string     `yaml:"discription"`
+	Version     string     `yaml:"version"`
+	Runtime     Runtime    `yaml:"runtime"`
+	Resolution  Resolution `yaml:"resolution"`
+	Orientation string     `yaml:"orientation"`
+}
+
+// 游戏的屏幕方向，元素中的坐标都是这个方向上的坐标
+// 为空时使用设备当前的方向
+const (
+	OrientationPortrait  = "portrait"
+	OrientationLandscape = "landscape"
+)
+
+// 工程的基准分辨率，也就是编写元素时所使用设备的屏幕分辨率
+// 为零值时元素中的坐标不会被缩放
+type Resolution struct {
+	Width  int `yaml:"width"`
+	Height int `yaml:"height"`
+}
+
+func (r Resolution) Point() image.Point {
+	return image.Pt(r.Width, r.Height)
 }
 
 // 脚本代码的运行环境，Name 就是 Name，例如 go，nodejs，python
@@ -31,10 +52,10 @@ type Runtime struct {
 	RunLinux      string `yaml:"run_linux"`
 }
 
-// 从 file 加载 json 文件，反序列化成 Info 并验证 Info 的正确性
+// 从 fsys 中的 file 加载 yaml 文件，反序列化成 Info 并验证 Info 的正确性
 // 内部已经调用了 VerifyInfo
-func LoadInfo(file string) (*Info, error) {
-	infoB, err := os.ReadFile(file)
+func LoadInfo(fsys fs.FS, file string) (*Info, error) {
+	infoB, err := fs.ReadFile(fsys, file)
 	info := new(Info)
 	makeErr := func(err error) error {
 		return fmt.Errorf("failed to load info: %w", err)
@@ -79,6 +100,8 @@ func LoadInfo(file string) (*Info, error) {
 
 // 检查 Info 中的内容是否符合要求：
 // - Name, Runtime.Name, Runtime.Run 不能为空
+// - Resolution 的 Width 和 Height 要么都为 0，要么都大于 0
+// - Orientation 为空或者是 OrientationPortrait, OrientationLandscape，并且与 Resolution 的方向相同
 func VerifyInfo(info Info) error {
 	if info.Name == "" {
 		return fmt.Errorf("field [name] cannot be empty in info")
@@ -89,5 +112,22 @@ func VerifyInfo(info Info) error {
 	if info.Runtime.Run == "" {
 		return fmt.Errorf("field [runtime.run] cannot be empty in info")
 	}
+	r := info.Resolution
+	if !(r.Width == 0 && r.Height == 0) && !(r.Width > 0 && r.Height > 0) {
+		return fmt.Errorf("field [resolution] must be both zero or both positive in info")
+	}
+	switch info.Orientation {
+	case "":
+	case OrientationPortrait:
+		if r.Width > r.Height {
+			return fmt.Errorf("field [resolution] is landscape, but [orientation] is portrait in info")
+		}
+	case OrientationLandscape:
+		if r.Width < r.Height {
+			return fmt.Errorf("field [resolution] is portrait, but [orientation] is landscape in info")
+		}
+	default:
+		return fmt.Errorf("orientation [%s] undefiend", info.Orientation)
+	}
 	return nil
 }
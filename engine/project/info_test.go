@@ -1,6 +1,7 @@
 package project_test
 
 import (
+	"os"
 	"testing"
 
 	"github.com/HumXC/give-me-time/engine/project"
@@ -35,6 +36,31 @@ func TestVerifyInfo(t *testing.T) {
 			Run:  "",
 		},
 	}
+	bad4 := project.Info{
+		Name: "ddds",
+		Runtime: project.Runtime{
+			Name: "ds",
+			Run:  "go rum",
+		},
+		Resolution: project.Resolution{Width: 1080},
+	}
+	bad5 := project.Info{
+		Name: "ddds",
+		Runtime: project.Runtime{
+			Name: "ds",
+			Run:  "go rum",
+		},
+		Resolution:  project.Resolution{Width: 1080, Height: 2400},
+		Orientation: project.OrientationLandscape,
+	}
+	bad6 := project.Info{
+		Name: "ddds",
+		Runtime: project.Runtime{
+			Name: "ds",
+			Run:  "go rum",
+		},
+		Orientation: "upside-down",
+	}
 	err := project.VerifyInfo(good)
 	if err != nil {
 		t.Error(err)
@@ -55,9 +81,24 @@ func TestVerifyInfo(t *testing.T) {
 		t.Error("case [bad3] should be an error")
 		return
 	}
+	err = project.VerifyInfo(bad4)
+	if err == nil {
+		t.Error("case [bad4] should be an error")
+		return
+	}
+	err = project.VerifyInfo(bad5)
+	if err == nil {
+		t.Error("case [bad5] should be an error")
+		return
+	}
+	err = project.VerifyInfo(bad6)
+	if err == nil {
+		t.Error("case [bad6] should be an error")
+		return
+	}
 }
 func TestLoadInfo(t *testing.T) {
-	info, err := project.LoadInfo("info_test.yaml")
+	info, err := project.LoadInfo(os.DirFS("."), "info_test.yaml")
 	if err != nil {
 		t.Fatal(err)
 	}
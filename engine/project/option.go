@@ -4,20 +4,96 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"io/fs"
+	"math"
 	"os"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// 用户选项文件所在的目录，每个文件是一个 profile，例如 profiles/alt-account.yaml
+// 用户选项文件可能含有密码等信息，所以 Pack 不会打包这个目录
+const (
+	ProfileDir     = "profiles"
+	DefaultProfile = "default"
 )
 
+// 选项文件支持的扩展名，按照优先级排序
+var optionExts = []string{".yaml", ".yml", ".json"}
+
+// Option 的类型
+const (
+	OptionString = "string"
+	OptionNumber = "number"
+	OptionBool   = "bool"
+	// 整数，可以使用 Min 和 Max 限制范围
+	OptionInt = "int"
+	// 只能是 Enum 中的一个 Value
+	OptionEnum = "enum"
+	// 一组值，值的类型是 Item
+	OptionList = "list"
+	// time.ParseDuration 格式的时长，例如 "1h30m"
+	OptionDuration = "duration"
+	// 一天中的时间，格式为 "15:04" 或者 "15:04:05"
+	OptionTime = "time"
+	// 与 string 相同，但是在日志中会被 MaskOption 隐藏
+	OptionSecret = "secret"
+)
+
+// 可以作为 list 元素的类型
+var optionItemTypes = []string{OptionString, OptionNumber, OptionBool, OptionInt, OptionDuration, OptionTime}
+
+// IsOnlySelect 为 true 时，用户的值只能是 Select 中的一个，Type 为 list 时对每一个元素生效
+// Min 和 Max 用于限制 int 和 number 的范围，Type 为 list 时用于限制元素的范围
 type Option struct {
-	Name         string `yaml:"name"`
-	Type         string `yaml:"type"` // string, number, bool
-	Discription  string `yaml:"discription"`
-	Select       []any  `yaml:"select"`
-	IsOnlySelect bool   `yaml:"is_only_select"`
-	Default      any    `yaml:"default"`
+	Name         string     `yaml:"name" json:"name"`
+	Type         string     `yaml:"type" json:"type"`
+	Discription  string     `yaml:"discription" json:"discription"`
+	Select       []any      `yaml:"select" json:"select"`
+	IsOnlySelect bool       `yaml:"is_only_select" json:"is_only_select"`
+	Default      any        `yaml:"default" json:"default"`
+	Min          *float64   `yaml:"min" json:"min"`
+	Max          *float64   `yaml:"max" json:"max"`
+	Enum         []EnumItem `yaml:"enum" json:"enum"`
+	Item         string     `yaml:"item" json:"item"`
+}
+
+// enum 的一个值，Label 是显示给用户的名称，为空时使用 Value
+type EnumItem struct {
+	Value string `yaml:"value" json:"value"`
+	Label string `yaml:"label" json:"label"`
+}
+
+// 一个不合法的选项或者用户的值
+type OptionError struct {
+	Name string
+	Msg  string
+}
+
+func (e OptionError) Error() string {
+	return fmt.Sprintf("[%s] %s", e.Name, e.Msg)
+}
+
+// 所有不合法的选项或者用户的值
+type OptionErrors []OptionError
+
+func (es OptionErrors) Error() string {
+	msgs := make([]string, 0, len(es))
+	for _, e := range es {
+		msgs = append(msgs, e.Error())
+	}
+	return "invalid option:\n\t" + strings.Join(msgs, "\n\t")
 }
 
-func LoadOption(file string) ([]Option, error) {
-	optB, err := os.ReadFile(file)
+// 从 fsys 中的 file 加载 yaml 或者 json 文件，反序列化成 Option 并验证 Option 的正确性
+// 内部已经调用了 VerifyOption，并为没有 Default 的 Option 赋予初值
+func LoadOption(fsys fs.FS, file string) ([]Option, error) {
+	optB, err := fs.ReadFile(fsys, file)
 	opts := make([]Option, 0)
 	makeErr := func(err error) error {
 		return fmt.Errorf("failed to load option: %w", err)
@@ -25,50 +101,61 @@ func LoadOption(file string) ([]Option, error) {
 	if err != nil {
 		return nil, makeErr(err)
 	}
-	err = json.Unmarshal(optB, &opts)
+	err = unmarshalOption(file, optB, &opts)
 	if err != nil {
 		return nil, makeErr(err)
 	}
-	err = VerifyOption(opts)
 	// 给 Default 赋予初值
 	for i := 0; i < len(opts); i++ {
-		var defaul any
-		switch opts[i].Type {
-		case "string":
-			defaul = ""
-		case "number":
-			defaul = 0.0
-		case "bool":
-			defaul = false
+		opts[i].Default = normalizeYAML(opts[i].Default)
+		for j := 0; j < len(opts[i].Select); j++ {
+			opts[i].Select[j] = normalizeYAML(opts[i].Select[j])
 		}
 		if opts[i].Default == nil {
-			opts[i].Default = defaul
+			opts[i].Default = zeroValue(opts[i])
 		}
 	}
+	err = VerifyOption(opts)
 	if err != nil {
 		return nil, makeErr(err)
 	}
 	return opts, nil
 }
 
+func zeroValue(opt Option) any {
+	switch opt.Type {
+	case OptionString, OptionSecret:
+		return ""
+	case OptionNumber:
+		return 0.0
+	case OptionInt:
+		return 0
+	case OptionBool:
+		return false
+	case OptionEnum:
+		if len(opt.Enum) != 0 {
+			return opt.Enum[0].Value
+		}
+	case OptionList:
+		return []any{}
+	case OptionDuration:
+		return "0s"
+	case OptionTime:
+		return "00:00"
+	}
+	return nil
+}
+
 // 检查 Option 中的内容是否符合要求：
 //   - Name 和 Type 不能为空
 //   - Name 不可重复
 //   - Default 和 Select 的值要符合 Type 的定义，
 //     如果 Type 为 string，那么 Default 就应该是 string，Select 就应该是 []string
+//   - Min 不能大于 Max，Default 不能超出 Min 和 Max 的范围
+//   - Type 为 enum 时 Enum 不能为空，Value 不能为空且不能重复
+//   - Type 为 list 时 Item 必须是 string, number, bool, int, duration, time 中的一个，
+//     Select 中的值要符合 Item 的定义
 func VerifyOption(opts []Option) error {
-	assert := func(v any, t string) bool {
-		var ok bool
-		switch t {
-		case "string":
-			_, ok = v.(string)
-		case "number":
-			_, ok = v.(float64)
-		case "bool":
-			_, ok = v.(bool)
-		}
-		return ok
-	}
 	keys := make(map[string]struct{})
 	for _, opt := range opts {
 		if _, ok := keys[opt.Name]; ok {
@@ -81,15 +168,46 @@ func VerifyOption(opts []Option) error {
 		if t == "" {
 			return errors.New("[type] cannot be empty")
 		}
-		if !(t == "number" || t == "string" || t == "bool") {
-			return errors.New("[type] must be [string|number|bool]")
+		switch t {
+		case OptionString, OptionNumber, OptionBool, OptionInt, OptionEnum,
+			OptionList, OptionDuration, OptionTime, OptionSecret:
+		default:
+			return errors.New("[type] must be [string|number|bool|int|enum|list|duration|time|secret]")
+		}
+		if opt.Min != nil && opt.Max != nil && *opt.Min > *opt.Max {
+			return fmt.Errorf("[name:%s] [min:%v] is greater than [max:%v]", opt.Name, *opt.Min, *opt.Max)
+		}
+		if t == OptionEnum {
+			if len(opt.Enum) == 0 {
+				return fmt.Errorf("[name:%s] [enum] cannot be empty", opt.Name)
+			}
+			values := make(map[string]struct{})
+			for _, e := range opt.Enum {
+				if _, ok := values[e.Value]; ok || e.Value == "" {
+					return fmt.Errorf("[name:%s] enum value [%s] is empty or repeated", opt.Name, e.Value)
+				}
+				values[e.Value] = struct{}{}
+			}
+		}
+		itemType := t
+		if t == OptionList {
+			itemType = opt.Item
+			ok := false
+			for _, it := range optionItemTypes {
+				ok = ok || it == itemType
+			}
+			if !ok {
+				return fmt.Errorf("[name:%s] [item] must be one of %v", opt.Name, optionItemTypes)
+			}
 		}
-		if !assert(opt.Default, opt.Type) {
-			return fmt.Errorf("[default:%v] does not match the [type:%s]", opt.Default, opt.Type)
+		_, err := checkValue(opt, t, opt.Default)
+		if err != nil {
+			return fmt.Errorf("[default:%v] does not match the [type:%s]: %w", opt.Default, opt.Type, err)
 		}
 		for _, s := range opt.Select {
-			if !assert(s, opt.Type) {
-				return fmt.Errorf("[select:%v] does not match the [type:%s]", s, opt.Type)
+			_, err := checkValue(opt, itemType, s)
+			if err != nil {
+				return fmt.Errorf("[select:%v] does not match the [type:%s]: %w", s, itemType, err)
 			}
 		}
 		keys[opt.Name] = struct{}{}
@@ -97,44 +215,318 @@ func VerifyOption(opts []Option) error {
 	return nil
 }
 
-func ParseOption(opts []Option, userOption string) (map[string]any, error) {
-	optB, err := os.ReadFile(userOption)
-	makeErr := func(err error) error {
-		return fmt.Errorf("failed to parse option: %w", err)
+// 检查 v 是否符合类型 t，并返回转换后的值，int 会被转换成 int
+// json 中的数字都是 float64，所以 number 只接受 float64
+func checkValue(opt Option, t string, v any) (any, error) {
+	inRange := func(f float64) error {
+		if opt.Min != nil && f < *opt.Min {
+			return fmt.Errorf("[%v] is less than [min:%v]", f, *opt.Min)
+		}
+		if opt.Max != nil && f > *opt.Max {
+			return fmt.Errorf("[%v] is greater than [max:%v]", f, *opt.Max)
+		}
+		return nil
 	}
-	assert := func(v any, t string) bool {
-		var ok bool
-		switch t {
-		case "string":
-			_, ok = v.(string)
-		case "number":
-			_, ok = v.(float64)
-		case "bool":
-			_, ok = v.(bool)
+	wrongType := fmt.Errorf("[%v] is not a %s", v, t)
+	switch t {
+	case OptionString, OptionSecret:
+		if _, ok := v.(string); ok {
+			return v, nil
+		}
+	case OptionBool:
+		if _, ok := v.(bool); ok {
+			return v, nil
 		}
-		return ok
+	case OptionNumber:
+		if f, ok := v.(float64); ok {
+			return v, inRange(f)
+		}
+	case OptionInt:
+		var f float64
+		switch n := v.(type) {
+		case int:
+			f = float64(n)
+		case float64:
+			f = n
+		default:
+			return nil, wrongType
+		}
+		if f != math.Trunc(f) {
+			return nil, wrongType
+		}
+		return int(f), inRange(f)
+	case OptionEnum:
+		s, ok := v.(string)
+		if !ok {
+			return nil, wrongType
+		}
+		for _, e := range opt.Enum {
+			if e.Value == s {
+				return v, nil
+			}
+		}
+		return nil, fmt.Errorf("[%s] is not one of the enum values", s)
+	case OptionDuration:
+		s, ok := v.(string)
+		if !ok {
+			return nil, wrongType
+		}
+		_, err := time.ParseDuration(s)
+		if err != nil {
+			return nil, err
+		}
+		return v, nil
+	case OptionTime:
+		s, ok := v.(string)
+		if !ok {
+			return nil, wrongType
+		}
+		_, err := time.Parse("15:04", s)
+		if err != nil {
+			_, err = time.Parse("15:04:05", s)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("[%s] is not a time of day like 15:04 or 15:04:05", s)
+		}
+		return v, nil
+	case OptionList:
+		items, ok := v.([]any)
+		if !ok {
+			return nil, wrongType
+		}
+		result := make([]any, 0, len(items))
+		for _, item := range items {
+			r, err := checkValue(opt, opt.Item, item)
+			if err != nil {
+				return nil, err
+			}
+			result = append(result, r)
+		}
+		return result, nil
 	}
-	if err != nil {
-		return nil, makeErr(err)
+	return nil, wrongType
+}
+
+// 检查 v 是否是 opt.Select 中的一个，Type 为 list 时检查每一个元素
+func checkSelect(opt Option, v any) error {
+	itemType := opt.Type
+	items := []any{v}
+	if opt.Type == OptionList {
+		itemType = opt.Item
+		items, _ = v.([]any)
+	}
+	for _, item := range items {
+		found := false
+		for _, s := range opt.Select {
+			s, err := checkValue(opt, itemType, s)
+			if err == nil && s == item {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return fmt.Errorf("[%v] is not one of %v", item, opt.Select)
+		}
+	}
+	return nil
+}
+
+// 读取 yaml 或者 json 格式的用户选项文件 userOption，返回每个选项的值，用户没有设置的选项使用 Default
+// userOption 为空时所有选项都使用 Default
+// 会检查所有的值，有不合法的值时返回 OptionErrors
+func ParseOption(opts []Option, userOption string) (map[string]any, error) {
+	makeErr := func(err error) error {
+		return fmt.Errorf("failed to parse option: %w", err)
 	}
 	m := make(map[string]any, 0)
-	err = json.Unmarshal(optB, &m)
-	if err != nil {
-		return nil, makeErr(err)
+	if userOption != "" {
+		optB, err := os.ReadFile(userOption)
+		if err != nil {
+			return nil, makeErr(err)
+		}
+		err = unmarshalOption(userOption, optB, &m)
+		if err != nil {
+			return nil, makeErr(err)
+		}
+		for k, v := range m {
+			m[k] = normalizeYAML(v)
+		}
 	}
 	result := make(map[string]any)
+	errs := make(OptionErrors, 0)
 	for _, opt := range opts {
 		v, ok := m[opt.Name]
 		if !ok {
-			result[opt.Name] = opt.Default
+			result[opt.Name], _ = checkValue(opt, opt.Type, opt.Default)
 			continue
 		}
-		if assert(v, opt.Type) {
-			result[opt.Name] = v
+		v, err := checkValue(opt, opt.Type, v)
+		if err == nil && opt.IsOnlySelect && len(opt.Select) != 0 {
+			err = checkSelect(opt, v)
+		}
+		if err != nil {
+			errs = append(errs, OptionError{Name: opt.Name, Msg: fmt.Sprintf("uses the [%s] type: %v", opt.Type, err)})
 			continue
-		} else {
-			return nil, fmt.Errorf("[name:%s] name uses the [%s] type", opt.Name, opt.Type)
 		}
+		result[opt.Name] = v
+	}
+	if len(errs) != 0 {
+		return nil, makeErr(errs)
 	}
 	return result, nil
 }
+
+// 返回 dir 中名为 name 的 profile 的路径，也就是 dir/profiles/name.yaml，
+// 扩展名可以是 .yaml, .yml 或者 .json
+// name 为 DefaultProfile 并且文件不存在时返回空字符串，此时 ParseOption 会使用默认值
+func ProfilePath(dir, name string) (string, error) {
+	if name == "" || strings.ContainsAny(name, `/\`) {
+		return "", fmt.Errorf("invalid profile name [%s]", name)
+	}
+	for _, ext := range optionExts {
+		p := filepath.Join(dir, ProfileDir, name+ext)
+		_, err := os.Stat(p)
+		if err == nil {
+			return p, nil
+		}
+	}
+	if name == DefaultProfile {
+		return "", nil
+	}
+	return "", fmt.Errorf("profile [%s] not found in [%s]", name, filepath.Join(dir, ProfileDir))
+}
+
+// 根据 file 的扩展名选择 json 或者 yaml
+func unmarshalOption(file string, b []byte, v any) error {
+	if strings.EqualFold(path.Ext(filepath.ToSlash(file)), ".json") {
+		return json.Unmarshal(b, v)
+	}
+	return yaml.Unmarshal(b, v)
+}
+
+// yaml 会把整数反序列化成 int，这里转换成 float64，与 json 保持一致
+func normalizeYAML(v any) any {
+	switch v := v.(type) {
+	case int:
+		return float64(v)
+	case []any:
+		for i := range v {
+			v[i] = normalizeYAML(v[i])
+		}
+	case map[string]any:
+		for k := range v {
+			v[k] = normalizeYAML(v[k])
+		}
+	}
+	return v
+}
+
+// 将用户在终端中输入的文字 s 转换成 opt 的值，并检查值是否合法
+//   - bool 可以是 true, false, yes, no, y, n
+//   - enum 可以是 Value 或者 Label
+//   - list 的元素使用逗号分隔
+func ParseOptionValue(opt Option, s string) (any, error) {
+	s = strings.TrimSpace(s)
+	parse := func(t, s string) (any, error) {
+		switch t {
+		case OptionNumber, OptionInt:
+			f, err := strconv.ParseFloat(s, 64)
+			if err != nil {
+				return nil, fmt.Errorf("[%s] is not a %s", s, t)
+			}
+			return f, nil
+		case OptionBool:
+			switch strings.ToLower(s) {
+			case "y", "yes":
+				return true, nil
+			case "n", "no":
+				return false, nil
+			}
+			b, err := strconv.ParseBool(s)
+			if err != nil {
+				return nil, fmt.Errorf("[%s] is not a bool", s)
+			}
+			return b, nil
+		case OptionEnum:
+			for _, e := range opt.Enum {
+				if e.Label != "" && e.Label == s {
+					return e.Value, nil
+				}
+			}
+		}
+		return s, nil
+	}
+	var v any
+	if opt.Type == OptionList {
+		items := make([]any, 0)
+		for _, item := range strings.Split(s, ",") {
+			item = strings.TrimSpace(item)
+			if item == "" {
+				continue
+			}
+			i, err := parse(opt.Item, item)
+			if err != nil {
+				return nil, err
+			}
+			items = append(items, i)
+		}
+		v = items
+	} else {
+		var err error
+		v, err = parse(opt.Type, s)
+		if err != nil {
+			return nil, err
+		}
+	}
+	v, err := checkValue(opt, opt.Type, v)
+	if err == nil && opt.IsOnlySelect && len(opt.Select) != 0 {
+		err = checkSelect(opt, v)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return v, nil
+}
+
+// 将 values 按照 file 的扩展名保存为 yaml 或者 json 格式的用户选项文件
+// profile 中可能有 secret 类型的选项，文件只有所有者可以读写
+func SaveProfile(file string, values map[string]any) error {
+	var b []byte
+	var err error
+	if strings.EqualFold(filepath.Ext(file), ".json") {
+		b, err = json.MarshalIndent(values, "", "    ")
+	} else {
+		b, err = yaml.Marshal(values)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to save profile: %w", err)
+	}
+	err = os.MkdirAll(filepath.Dir(file), 0755)
+	if err != nil {
+		return fmt.Errorf("failed to save profile: %w", err)
+	}
+	err = os.WriteFile(file, b, 0600)
+	if err != nil {
+		return fmt.Errorf("failed to save profile: %w", err)
+	}
+	// 已经存在的文件不会被 WriteFile 修改权限
+	err = os.Chmod(file, 0600)
+	if err != nil {
+		return fmt.Errorf("failed to save profile: %w", err)
+	}
+	return nil
+}
+
+// 返回 values 的副本，其中 secret 类型的值被替换为 "******"，用于输出日志
+func MaskOption(opts []Option, values map[string]any) map[string]any {
+	result := make(map[string]any, len(values))
+	for k, v := range values {
+		result[k] = v
+	}
+	for _, opt := range opts {
+		if _, ok := result[opt.Name]; ok && opt.Type == OptionSecret {
+			result[opt.Name] = "******"
+		}
+	}
+	return result
+}
@@ -1,7 +1,12 @@
 package project_test
 
 import (
+	"errors"
+	"os"
+	"path/filepath"
+	"runtime"
 	"testing"
+	"testing/fstest"
 
 	"github.com/HumXC/give-me-time/engine/project"
 )
@@ -99,3 +104,208 @@ func TestParseOption(t *testing.T) {
 		}
 	}
 }
+
+func float(f float64) *float64 { return &f }
+
+func typedOptions() []project.Option {
+	return []project.Option{
+		{Name: "level", Type: project.OptionInt, Default: 1.0, Min: float(1), Max: float(5)},
+		{Name: "mode", Type: project.OptionEnum, Default: "easy", Enum: []project.EnumItem{{Value: "easy", Label: "简单"}, {Value: "hard"}}},
+		{Name: "days", Type: project.OptionList, Item: project.OptionString, Default: []any{}, Select: []any{"mon", "fri"}, IsOnlySelect: true},
+		{Name: "every", Type: project.OptionDuration, Default: "30m"},
+		{Name: "at", Type: project.OptionTime, Default: "04:00"},
+		{Name: "token", Type: project.OptionSecret, Default: ""},
+		{Name: "server", Type: project.OptionString, Default: "cn", Select: []any{"cn", "jp"}, IsOnlySelect: true},
+	}
+}
+
+func TestVerifyOptionTypes(t *testing.T) {
+	err := project.VerifyOption(typedOptions())
+	if err != nil {
+		t.Fatal(err)
+	}
+	bads := map[string]project.Option{
+		"int":      {Name: "a", Type: project.OptionInt, Default: 1.5},
+		"range":    {Name: "a", Type: project.OptionInt, Default: 9.0, Max: float(5)},
+		"min>max":  {Name: "a", Type: project.OptionNumber, Default: 1.0, Min: float(2), Max: float(1)},
+		"enum":     {Name: "a", Type: project.OptionEnum, Default: "c", Enum: []project.EnumItem{{Value: "a"}}},
+		"no enum":  {Name: "a", Type: project.OptionEnum, Default: ""},
+		"item":     {Name: "a", Type: project.OptionList, Item: project.OptionList, Default: []any{}},
+		"list":     {Name: "a", Type: project.OptionList, Item: project.OptionInt, Default: []any{"a"}},
+		"select":   {Name: "a", Type: project.OptionList, Item: project.OptionInt, Default: []any{}, Select: []any{"a"}},
+		"duration": {Name: "a", Type: project.OptionDuration, Default: "1 day"},
+		"time":     {Name: "a", Type: project.OptionTime, Default: "4pm"},
+		"secret":   {Name: "a", Type: project.OptionSecret, Default: 1.0},
+	}
+	for k, v := range bads {
+		err := project.VerifyOption([]project.Option{v})
+		if err == nil {
+			t.Error("case [" + k + "] should be an error, but not")
+		}
+	}
+}
+
+func TestParseOptionTypes(t *testing.T) {
+	opts := typedOptions()
+	m, err := project.ParseOption(opts, "user_option_types_test.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if m["level"] != 3 || m["mode"] != "hard" || m["every"] != "1h30m" || m["at"] != "04:30" || m["server"] != "cn" {
+		t.Errorf("unexpected result: %v", m)
+	}
+	if days, ok := m["days"].([]any); !ok || len(days) != 2 {
+		t.Errorf("want: [mon fri], got: %v", m["days"])
+	}
+	masked := project.MaskOption(opts, m)
+	if masked["token"] != "******" || m["token"] != "abc" {
+		t.Errorf("token should be masked only in the copy, got: %v, %v", masked["token"], m["token"])
+	}
+
+	// 所有不合法的值都要被报告
+	_, err = project.ParseOption(opts, "user_option_bad_test.json")
+	var errs project.OptionErrors
+	if !errors.As(err, &errs) {
+		t.Fatalf("want: OptionErrors, got: %v", err)
+	}
+	if len(errs) != 6 {
+		t.Errorf("want: 6 errors, got: %v", errs)
+	}
+}
+
+func TestLoadOptionYAML(t *testing.T) {
+	fsys := fstest.MapFS{"option.yaml": {Data: []byte(`
+- name: level
+  type: int
+  default: 2
+  min: 1
+- name: rate
+  type: number
+  default: 1
+  select: [1, 2.5]
+  is_only_select: true
+`)}}
+	opts, err := project.LoadOption(fsys, "option.yaml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(opts) != 2 || opts[0].Min == nil || !opts[1].IsOnlySelect || opts[1].Default != 1.0 {
+		t.Errorf("unexpected options: %+v", opts)
+	}
+}
+
+func TestProfilePath(t *testing.T) {
+	opts := typedOptions()
+	p, err := project.ProfilePath("profiles_test", "alt-account")
+	if err != nil {
+		t.Fatal(err)
+	}
+	m, err := project.ParseOption(opts, p)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if m["level"] != 2 || m["token"] != "alt" || m["at"] != "22:00" {
+		t.Errorf("unexpected result: %v", m)
+	}
+	p, err = project.ProfilePath("profiles_test", "main")
+	if err != nil {
+		t.Fatal(err)
+	}
+	m, err = project.ParseOption(opts, p)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if m["level"] != 4 || m["server"] != "jp" || m["mode"] != "easy" {
+		t.Errorf("unexpected result: %v", m)
+	}
+
+	// 没有 default 时使用默认值
+	p, err = project.ProfilePath("profiles_test", project.DefaultProfile)
+	if err != nil || p != "" {
+		t.Fatalf("want: empty path, got: %s, %v", p, err)
+	}
+	m, err = project.ParseOption(opts, p)
+	if err != nil || m["level"] != 1 {
+		t.Errorf("unexpected result: %v, %v", m, err)
+	}
+	for _, name := range []string{"missing", "../profiles_test", ""} {
+		_, err = project.ProfilePath("profiles_test", name)
+		if err == nil {
+			t.Error("case [" + name + "] should be an error, but not")
+		}
+	}
+}
+
+func TestParseOptionValue(t *testing.T) {
+	opts := make(map[string]project.Option)
+	for _, opt := range typedOptions() {
+		opts[opt.Name] = opt
+	}
+	opts["bool"] = project.Option{Name: "bool", Type: project.OptionBool}
+	goods := []struct {
+		name, input string
+		want        any
+	}{
+		{"level", " 3 ", 3},
+		{"mode", "hard", "hard"},
+		{"mode", "简单", "easy"},
+		{"every", "2h", "2h"},
+		{"at", "08:15", "08:15"},
+		{"bool", "y", true},
+		{"bool", "false", false},
+	}
+	for _, g := range goods {
+		v, err := project.ParseOptionValue(opts[g.name], g.input)
+		if err != nil || v != g.want {
+			t.Errorf("[%s] want: %v, got: %v, %v", g.name, g.want, v, err)
+		}
+	}
+	v, err := project.ParseOptionValue(opts["days"], "mon, fri,")
+	if days, ok := v.([]any); err != nil || !ok || len(days) != 2 {
+		t.Errorf("want: [mon fri], got: %v, %v", v, err)
+	}
+	bads := map[string]string{
+		"level":  "2.5",
+		"mode":   "nightmare",
+		"days":   "sun",
+		"every":  "soon",
+		"at":     "25:00",
+		"bool":   "maybe",
+		"server": "us",
+	}
+	for k, s := range bads {
+		_, err := project.ParseOptionValue(opts[k], s)
+		if err == nil {
+			t.Error("case [" + k + "] should be an error, but not")
+		}
+	}
+}
+
+func TestSaveProfile(t *testing.T) {
+	opts := typedOptions()
+	for _, ext := range []string{".yaml", ".json"} {
+		file := filepath.Join(t.TempDir(), project.ProfileDir, "test"+ext)
+		values := map[string]any{"level": 5, "days": []any{"fri"}, "token": "abc"}
+		err := project.SaveProfile(file, values)
+		if err != nil {
+			t.Fatal(err)
+		}
+		m, err := project.ParseOption(opts, file)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if m["level"] != 5 || m["token"] != "abc" || m["mode"] != "easy" {
+			t.Errorf("[%s] unexpected result: %v", ext, m)
+		}
+		if runtime.GOOS == "windows" {
+			continue
+		}
+		stat, err := os.Stat(file)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if stat.Mode().Perm() != 0600 {
+			t.Errorf("[%s] want: -rw-------, got: %s", ext, stat.Mode().Perm())
+		}
+	}
+}
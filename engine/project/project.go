@@ -0,0 +1,145 @@
+package project
+
+import (
+	"errors"
+	"fmt"
+	"image"
+	"io/fs"
+	"path"
+	"strings"
+)
+
+// 工程中的目录，目录中的每一个 yaml 文件都会被加载
+const (
+	ElementDir = "element"
+	SceneDir   = "scene"
+	FlowDir    = "flow"
+)
+
+// 工程中的选项文件，可以不存在，扩展名也可以是 .yaml 或者 .yml
+const OptionFile = "option.json"
+
+// Project 是一个已经加载并验证过的工程，工程的结构如下：
+//
+//	info.yaml
+//	option.yaml 或者 option.json
+//	element/*.yaml
+//	scene/*.yaml
+//	flow/*.yaml
+//
+// 除了 info.yaml，其他文件和目录都可以不存在
+// Element 中的 Img 是 FS 中的路径
+type Project struct {
+	FS      fs.FS
+	Info    Info
+	Option  []Option
+	Element []Element
+	Scene   []Scene
+	Flow    []Flow
+}
+
+// 从 fsys 加载工程，fsys 可以是 os.DirFS，embed.FS，OpenArchive 打开的工程等
+func Open(fsys fs.FS) (*Project, error) {
+	makeErr := func(err error) error {
+		return fmt.Errorf("failed to open project: %w", err)
+	}
+	info, err := LoadInfo(fsys, InfoFile)
+	if err != nil {
+		return nil, makeErr(err)
+	}
+	p := &Project{FS: fsys, Info: *info}
+
+	for _, ext := range optionExts {
+		f := strings.TrimSuffix(OptionFile, path.Ext(OptionFile)) + ext
+		_, err = fs.Stat(fsys, f)
+		if errors.Is(err, fs.ErrNotExist) {
+			continue
+		}
+		if err == nil {
+			p.Option, err = LoadOption(fsys, f)
+		}
+		if err != nil {
+			return nil, makeErr(err)
+		}
+		break
+	}
+
+	files, err := yamlFiles(fsys, ElementDir)
+	if err != nil {
+		return nil, makeErr(err)
+	}
+	for _, f := range files {
+		es, err := LoadElement(fsys, f)
+		if err != nil {
+			return nil, makeErr(fmt.Errorf("%s: %w", f, err))
+		}
+		p.Element = append(p.Element, es...)
+	}
+	// 不同文件中的元素名称也不能重复
+	err = VerifyElement("", p.Element)
+	if err != nil {
+		return nil, makeErr(err)
+	}
+
+	files, err = yamlFiles(fsys, SceneDir)
+	if err != nil {
+		return nil, makeErr(err)
+	}
+	for _, f := range files {
+		s, err := LoadScene(fsys, f)
+		if err != nil {
+			return nil, makeErr(fmt.Errorf("%s: %w", f, err))
+		}
+		p.Scene = append(p.Scene, s...)
+	}
+	err = VerifyScene(p.Scene)
+	if err != nil {
+		return nil, makeErr(err)
+	}
+	err = VerifySceneElement(p.Scene, p.Element)
+	if err != nil {
+		return nil, makeErr(err)
+	}
+
+	files, err = yamlFiles(fsys, FlowDir)
+	if err != nil {
+		return nil, makeErr(err)
+	}
+	for _, f := range files {
+		flow, err := LoadFlow(fsys, f)
+		if err != nil {
+			return nil, makeErr(fmt.Errorf("%s: %w", f, err))
+		}
+		p.Flow = append(p.Flow, *flow)
+	}
+	err = VerifyFlowElement(p.Flow, p.Element)
+	if err != nil {
+		return nil, makeErr(err)
+	}
+	return p, nil
+}
+
+// 解析工程中的元素，screen 是设备的分辨率，参考 ParseElement
+func (p *Project) ParseElement(screen image.Point) (map[string]ElImg, map[string]ElArea, map[string]ElPoint, map[string]ElColor, error) {
+	return ParseElement(p.FS, p.Element, Scaler{Ref: p.Info.Resolution.Point(), Screen: screen})
+}
+
+// 返回 dir 中所有的 yaml 文件，dir 不存在时返回空
+func yamlFiles(fsys fs.FS, dir string) ([]string, error) {
+	entries, err := fs.ReadDir(fsys, dir)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	files := make([]string, 0, len(entries))
+	for _, e := range entries {
+		ext := path.Ext(e.Name())
+		if e.IsDir() || !(strings.EqualFold(ext, ".yaml") || strings.EqualFold(ext, ".yml")) {
+			continue
+		}
+		files = append(files, path.Join(dir, e.Name()))
+	}
+	return files, nil
+}
@@ -0,0 +1,74 @@
+package project_test
+
+import (
+	"image"
+	"os"
+	"testing"
+	"testing/fstest"
+
+	"github.com/HumXC/give-me-time/engine/project"
+)
+
+func TestOpen(t *testing.T) {
+	fsys := testProject(t)
+	fsys["option.json"] = &fstest.MapFile{Data: []byte(`[{"name": "times", "type": "number", "default": 1}]`)}
+	fsys["element/home.yml"] = &fstest.MapFile{Data: []byte("- name: home\n  area: {x1: 0, y1: 0, x2: 10, y2: 10}\n")}
+	fsys["element/readme.md"] = &fstest.MapFile{Data: []byte("not an element")}
+	fsys["scene/main.yaml"] = &fstest.MapFile{Data: []byte("- name: home\n  require:\n    - element: button\n")}
+	fsys["flow/daily.yaml"] = &fstest.MapFile{Data: []byte("states:\n  - name: home\n    scene: home\n    end: true\n")}
+	p, err := project.Open(fsys)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p.Info.Name != "test" || len(p.Option) != 1 || len(p.Element) != 2 || len(p.Scene) != 1 || len(p.Flow) != 1 {
+		t.Fatalf("unexpected project: %+v", p)
+	}
+	elImg, elArea, _, _, err := p.ParseElement(image.ZP)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(elImg["button"].Img) == 0 || len(elArea) != 1 {
+		t.Errorf("unexpected elements: %v, %v", elImg, elArea)
+	}
+
+	// 不同文件中的元素重名
+	fsys["element/dup.yaml"] = &fstest.MapFile{Data: []byte("- name: home\n  point: {x: 0, y: 0}\n")}
+	_, err = project.Open(fsys)
+	if err == nil {
+		t.Error("case [dup] should be an error, but not")
+	}
+
+	// 只有 info.yaml 的工程
+	_, err = project.Open(fstest.MapFS{"info.yaml": fsys["info.yaml"]})
+	if err != nil {
+		t.Error(err)
+	}
+	_, err = project.Open(os.DirFS("img_test"))
+	if err == nil {
+		t.Error("case [no info] should be an error, but not")
+	}
+}
+
+func TestPatchAbsPath(t *testing.T) {
+	es := []project.Element{
+		{Img: "a.png"},
+		{Img: "../img/b.png"},
+		{Img: "/img/c.png"},
+		{Img: `img\d.png`},
+		{Variant: []project.Variant{{Img: "e.png"}}},
+		{Element: []project.Element{{Img: "f.png"}}},
+	}
+	project.PatchAbsPath(es, "element")
+	want := []string{"element/a.png", "img/b.png", "img/c.png", "element/img/d.png"}
+	for i, w := range want {
+		if es[i].Img != w {
+			t.Errorf("want: %s, got: %s", w, es[i].Img)
+		}
+	}
+	if es[4].Variant[0].Img != "element/e.png" {
+		t.Errorf("want: element/e.png, got: %s", es[4].Variant[0].Img)
+	}
+	if es[5].Element[0].Img != "element/f.png" {
+		t.Errorf("want: element/f.png, got: %s", es[5].Element[0].Img)
+	}
+}
@@ -0,0 +1,116 @@
+package project
+
+import (
+	"image"
+	"math"
+)
+
+// 元素坐标的单位
+const (
+	// 基准分辨率下的像素，默认值
+	UnitPx = "px"
+	// 基准分辨率的比例，取值范围是 0 - 1
+	UnitRatio = "ratio"
+	// 基准分辨率的百分比，取值范围是 0 - 100
+	UnitPercent = "percent"
+)
+
+// 元素坐标的锚点
+// AnchorStretch 时 x 和 y 分别按照宽和高的比例缩放，适用于随屏幕拉伸的界面
+// 其他锚点按照宽高中较小的比例等比缩放，并保持与锚点的相对位置不变，
+// 适用于刘海屏或者有黑边的界面，例如固定在右下角的按钮
+const (
+	AnchorStretch     = "stretch"
+	AnchorTopLeft     = "top_left"
+	AnchorTopRight    = "top_right"
+	AnchorBottomLeft  = "bottom_left"
+	AnchorBottomRight = "bottom_right"
+	AnchorCenter      = "center"
+)
+
+// 一个点，单位由 Element.Unit 决定，所以允许是小数
+type Point struct {
+	X float64 `yaml:"x"`
+	Y float64 `yaml:"y"`
+}
+
+// Scaler 将基准分辨率 Ref 下的坐标转换成屏幕 Screen 上的像素坐标
+// Ref 为零值时认为与 Screen 相同，Screen 为零值时认为与 Ref 相同
+type Scaler struct {
+	Ref    image.Point
+	Screen image.Point
+}
+
+func (s Scaler) normalize() (image.Point, image.Point) {
+	ref, screen := s.Ref, s.Screen
+	if ref.Eq(image.ZP) {
+		ref = screen
+	}
+	if screen.Eq(image.ZP) {
+		screen = ref
+	}
+	return ref, screen
+}
+
+// 将 unit 单位的值转换成基准分辨率下的像素
+func (s Scaler) toRef(x, y float64, unit string) (float64, float64) {
+	ref, _ := s.normalize()
+	switch unit {
+	case UnitRatio:
+		return x * float64(ref.X), y * float64(ref.Y)
+	case UnitPercent:
+		return x / 100 * float64(ref.X), y / 100 * float64(ref.Y)
+	}
+	return x, y
+}
+
+// x 和 y 方向的缩放比例
+func (s Scaler) scale(anchor string) (float64, float64) {
+	ref, screen := s.normalize()
+	if ref.X == 0 || ref.Y == 0 {
+		return 1, 1
+	}
+	sx := float64(screen.X) / float64(ref.X)
+	sy := float64(screen.Y) / float64(ref.Y)
+	if anchor == "" || anchor == AnchorStretch {
+		return sx, sy
+	}
+	m := math.Min(sx, sy)
+	return m, m
+}
+
+// 锚点在宽高为 size 的屏幕上的位置
+func anchorPoint(anchor string, size image.Point) (float64, float64) {
+	w, h := float64(size.X), float64(size.Y)
+	switch anchor {
+	case AnchorTopRight:
+		return w, 0
+	case AnchorBottomLeft:
+		return 0, h
+	case AnchorBottomRight:
+		return w, h
+	case AnchorCenter:
+		return w / 2, h / 2
+	}
+	return 0, 0
+}
+
+// 转换一个点的坐标
+func (s Scaler) Point(p Point, unit, anchor string) image.Point {
+	ref, screen := s.normalize()
+	x, y := s.toRef(p.X, p.Y, unit)
+	sx, sy := s.scale(anchor)
+	rx, ry := anchorPoint(anchor, ref)
+	dx, dy := anchorPoint(anchor, screen)
+	return image.Pt(
+		int(math.Round(dx+(x-rx)*sx)),
+		int(math.Round(dy+(y-ry)*sy)),
+	)
+}
+
+// 转换一个偏移量，与 Point 不同的是偏移量不受锚点位置的影响
+func (s Scaler) Vector(p Point, unit, anchor string) image.Point {
+	x, y := s.toRef(p.X, p.Y, unit)
+	sx, sy := s.scale(anchor)
+	return image.Pt(int(math.Round(x*sx)), int(math.Round(y*sy)))
+}
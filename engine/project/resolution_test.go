@@ -0,0 +1,55 @@
+package project_test
+
+import (
+	"image"
+	"testing"
+
+	"github.com/HumXC/give-me-time/engine/project"
+)
+
+func TestScalerPoint(t *testing.T) {
+	// 1080x2400 的工程运行在 720x1280 的设备上
+	s := project.Scaler{Ref: image.Pt(1080, 2400), Screen: image.Pt(720, 1280)}
+	cases := []struct {
+		p      project.Point
+		unit   string
+		anchor string
+		want   image.Point
+	}{
+		{project.Point{X: 540, Y: 1200}, "", "", image.Pt(360, 640)},
+		{project.Point{X: 540, Y: 1200}, project.UnitPx, project.AnchorStretch, image.Pt(360, 640)},
+		{project.Point{X: 0.5, Y: 0.25}, project.UnitRatio, "", image.Pt(360, 320)},
+		{project.Point{X: 50, Y: 25}, project.UnitPercent, "", image.Pt(360, 320)},
+		// 等比缩放的比例是 min(720/1080, 1280/2400) = 0.5333
+		{project.Point{X: 0, Y: 0}, project.UnitPx, project.AnchorTopLeft, image.Pt(0, 0)},
+		{project.Point{X: 1080, Y: 2400}, project.UnitPx, project.AnchorBottomRight, image.Pt(720, 1280)},
+		{project.Point{X: 1080 - 150, Y: 2400 - 300}, project.UnitPx, project.AnchorBottomRight, image.Pt(720-80, 1280-160)},
+		{project.Point{X: 150, Y: 2400 - 300}, project.UnitPx, project.AnchorBottomLeft, image.Pt(80, 1280-160)},
+		{project.Point{X: 540 + 150, Y: 1200}, project.UnitPx, project.AnchorCenter, image.Pt(360+80, 640)},
+	}
+	for i, c := range cases {
+		got := s.Point(c.p, c.unit, c.anchor)
+		if !got.Eq(c.want) {
+			t.Errorf("case [%d] want: %v, got: %v", i, c.want, got)
+		}
+	}
+
+	// 没有基准分辨率时，px 不会缩放，ratio 使用设备的分辨率
+	s = project.Scaler{Screen: image.Pt(720, 1280)}
+	if got := s.Point(project.Point{X: 100, Y: 200}, "", ""); !got.Eq(image.Pt(100, 200)) {
+		t.Errorf("want: %v, got: %v", image.Pt(100, 200), got)
+	}
+	if got := s.Point(project.Point{X: 0.5, Y: 0.5}, project.UnitRatio, ""); !got.Eq(image.Pt(360, 640)) {
+		t.Errorf("want: %v, got: %v", image.Pt(360, 640), got)
+	}
+}
+
+func TestScalerVector(t *testing.T) {
+	s := project.Scaler{Ref: image.Pt(1080, 2400), Screen: image.Pt(720, 1280)}
+	if got := s.Vector(project.Point{X: 150, Y: 300}, "", project.AnchorBottomRight); !got.Eq(image.Pt(80, 160)) {
+		t.Errorf("want: %v, got: %v", image.Pt(80, 160), got)
+	}
+	if got := s.Vector(project.Point{X: 108, Y: 240}, "", ""); !got.Eq(image.Pt(72, 128)) {
+		t.Errorf("want: %v, got: %v", image.Pt(72, 128), got)
+	}
+}
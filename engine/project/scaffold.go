@@ -0,0 +1,137 @@
+package project
+
+import (
+	"bytes"
+	"embed"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"text/template"
+)
+
+// 工程模板，根目录中是所有运行环境共用的文件，子目录 lua, go 等是对应运行环境的脚本
+// 以 .tmpl 结尾的文件会使用 text/template 渲染，并去掉 .tmpl 后缀
+//
+//go:embed template
+var templateFS embed.FS
+
+// Init 支持的运行环境
+var Runtimes = map[string]Runtime{
+	"lua": {
+		Name:   "lua",
+		Health: "lua -v",
+		Run:    "lua main.lua [HOST]:[PORT]",
+	},
+	"go": {
+		Name:   "go",
+		Health: "go version",
+		Run:    "go run main.go [HOST]:[PORT]",
+	},
+	"python": {
+		Name:          "python",
+		Health:        "python3 --version",
+		HealthWindows: "python --version",
+		Run:           "python3 main.py [HOST]:[PORT]",
+		RunWindows:    "python main.py [HOST]:[PORT]",
+	},
+	"node": {
+		Name:   "node",
+		Health: "node --version",
+		Run:    "node main.js [HOST]:[PORT]",
+	},
+}
+
+// 在 dir 中创建一个名为 name 的工程，runtime 是 Runtimes 中的一个
+// dir 不存在时会被创建，dir 已经存在时必须为空
+func Init(dir, name, runtime string) error {
+	makeErr := func(err error) error {
+		return fmt.Errorf("failed to init project: %w", err)
+	}
+	rt, ok := Runtimes[runtime]
+	if !ok {
+		names := make([]string, 0, len(Runtimes))
+		for k := range Runtimes {
+			names = append(names, k)
+		}
+		sort.Strings(names)
+		return makeErr(fmt.Errorf("runtime [%s] undefined %v", runtime, names))
+	}
+	if name == "" {
+		return makeErr(fmt.Errorf("project name cannot be empty"))
+	}
+	entries, err := os.ReadDir(dir)
+	if err == nil && len(entries) != 0 {
+		return makeErr(fmt.Errorf("directory [%s] is not empty", dir))
+	}
+	if err != nil && !os.IsNotExist(err) {
+		return makeErr(err)
+	}
+	data := struct {
+		Name    string
+		Runtime Runtime
+	}{name, rt}
+
+	files := map[string][]byte{
+		"element_schame.json": elementSchame,
+	}
+	err = fs.WalkDir(templateFS, "template", func(p string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+		rel := strings.TrimPrefix(p, "template/")
+		dst := rel
+		// 运行环境目录中的文件放到工程的根目录
+		if first, rest, ok := strings.Cut(rel, "/"); ok {
+			if _, isRuntime := Runtimes[first]; isRuntime {
+				if first != runtime {
+					return nil
+				}
+				dst = rest
+			}
+		}
+		b, err := fs.ReadFile(templateFS, p)
+		if err != nil {
+			return err
+		}
+		if strings.HasSuffix(dst, ".tmpl") {
+			dst = strings.TrimSuffix(dst, ".tmpl")
+			t, err := template.New(rel).Parse(string(b))
+			if err != nil {
+				return err
+			}
+			buf := new(bytes.Buffer)
+			err = t.Execute(buf, data)
+			if err != nil {
+				return err
+			}
+			b = buf.Bytes()
+		}
+		files[dst] = b
+		return nil
+	})
+	if err != nil {
+		return makeErr(err)
+	}
+	for f, b := range files {
+		dst := filepath.Join(dir, filepath.FromSlash(f))
+		err = os.MkdirAll(filepath.Dir(dst), 0755)
+		if err != nil {
+			return makeErr(err)
+		}
+		err = os.WriteFile(dst, b, 0644)
+		if err != nil {
+			return makeErr(err)
+		}
+	}
+	// 创建空的 scene, flow 和 img 目录，方便使用
+	for _, d := range []string{SceneDir, FlowDir, "img"} {
+		err = os.MkdirAll(filepath.Join(dir, d), 0755)
+		if err != nil {
+			return makeErr(err)
+		}
+	}
+	return nil
+}
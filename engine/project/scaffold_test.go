@@ -0,0 +1,56 @@
+package project_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/HumXC/give-me-time/engine/project"
+)
+
+func TestInit(t *testing.T) {
+	scripts := map[string]string{
+		"lua":    "main.lua",
+		"go":     "main.go",
+		"python": "main.py",
+		"node":   "main.js",
+	}
+	for rt, script := range scripts {
+		dir := filepath.Join(t.TempDir(), "new project")
+		err := project.Init(dir, "new: project", rt)
+		if err != nil {
+			t.Fatal(err)
+		}
+		p, err := project.Open(os.DirFS(dir))
+		if err != nil {
+			t.Fatalf("runtime [%s]: %v", rt, err)
+		}
+		if p.Info.Name != "new: project" || p.Info.Runtime.Name != rt || len(p.Element) == 0 || len(p.Option) == 0 {
+			t.Errorf("runtime [%s]: unexpected project: %+v", rt, p)
+		}
+		_, err = os.Stat(filepath.Join(dir, script))
+		if err != nil {
+			t.Error(err)
+		}
+		// 不属于这个运行环境的脚本
+		_, err = os.Stat(filepath.Join(dir, rt))
+		if err == nil {
+			t.Errorf("runtime [%s]: directory [%s] should not exist", rt, rt)
+		}
+	}
+
+	dir := t.TempDir()
+	err := project.Init(dir, "test", "ruby")
+	if err == nil {
+		t.Error("case [runtime] should be an error, but not")
+	}
+	err = project.Init(dir, "", "lua")
+	if err == nil {
+		t.Error("case [name] should be an error, but not")
+	}
+	_ = os.WriteFile(filepath.Join(dir, "a.txt"), nil, 0644)
+	err = project.Init(dir, "test", "lua")
+	if err == nil {
+		t.Error("case [not empty] should be an error, but not")
+	}
+}
@@ -0,0 +1,89 @@
+package project
+
+import (
+	"fmt"
+	"io/fs"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Scene 用于描述游戏中的一个画面
+// 当 Require 中的条件全部满足，并且 Forbid 中的条件全部不满足时，认为当前处于这个画面
+type Scene struct {
+	Name        string      `yaml:"name"`
+	Discription string      `yaml:"discription"`
+	Require     []Condition `yaml:"require"`
+	Forbid      []Condition `yaml:"forbid"`
+}
+
+// Condition 是对一个元素的判断，Element 是元素的路径，例如 "main.start"
+// - img 元素：能在屏幕上找到
+// - color 元素：颜色匹配
+// - area 元素：区域内的文字识别结果包含 Text
+type Condition struct {
+	Element string `yaml:"element"`
+	Text    string `yaml:"text"`
+}
+
+// 从 fsys 中的 file 加载 yaml 文件，反序列化成 Scene 并验证 Scene 的正确性
+// 内部已经调用了 VerifyScene
+func LoadScene(fsys fs.FS, file string) ([]Scene, error) {
+	s := make([]Scene, 0)
+	makeErr := func(err error) error {
+		return fmt.Errorf("failed to load scene: %w", err)
+	}
+	sB, err := fs.ReadFile(fsys, file)
+	if err != nil {
+		return nil, makeErr(err)
+	}
+	err = yaml.Unmarshal(sB, &s)
+	if err != nil {
+		return nil, makeErr(err)
+	}
+	err = VerifyScene(s)
+	if err != nil {
+		return nil, makeErr(err)
+	}
+	return s, nil
+}
+
+// 检查 Scene 中的内容是否符合要求：
+// - Name 不能为空且不能重复
+// - Require 不能为空
+// - Condition 的 Element 不能为空
+func VerifyScene(scenes []Scene) error {
+	m := make(map[string]struct{})
+	for _, s := range scenes {
+		if s.Name == "" {
+			return fmt.Errorf("scene name is empty")
+		}
+		if _, ok := m[s.Name]; ok {
+			return fmt.Errorf("scene name [%s] can not be repeat", s.Name)
+		}
+		if len(s.Require) == 0 {
+			return fmt.Errorf("scene [%s] must have at least one [require]", s.Name)
+		}
+		for _, c := range append(append([]Condition{}, s.Require...), s.Forbid...) {
+			if c.Element == "" {
+				return fmt.Errorf("scene [%s] has a condition without [element]", s.Name)
+			}
+		}
+		m[s.Name] = struct{}{}
+	}
+	return nil
+}
+
+// 检查 Scene 中的条件与元素是否对应：
+// - area 元素的条件必须有 Text，否则任何文字识别结果都满足条件
+func VerifySceneElement(scenes []Scene, es []Element) error {
+	m := make(map[string]Element)
+	FlatElement(m, "", es)
+	for _, s := range scenes {
+		for _, c := range append(append([]Condition{}, s.Require...), s.Forbid...) {
+			if e, ok := m[c.Element]; ok && e.Type == ElTypeArea && c.Text == "" {
+				return fmt.Errorf("scene [%s]: condition of area element [%s] must have [text]", s.Name, c.Element)
+			}
+		}
+	}
+	return nil
+}
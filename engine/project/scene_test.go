@@ -0,0 +1,74 @@
+package project_test
+
+import (
+	"os"
+	"testing"
+
+	"github.com/HumXC/give-me-time/engine/project"
+)
+
+func TestLoadScene(t *testing.T) {
+	s, err := project.LoadScene(os.DirFS("."), "scene_test.yaml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(s) != 2 {
+		t.Fatalf("want: 2 scenes, got: %d", len(s))
+	}
+	if s[0].Forbid[0].Text != "加载中" {
+		t.Errorf("want: [加载中], got: [%s]", s[0].Forbid[0].Text)
+	}
+}
+
+func TestVerifyScene(t *testing.T) {
+	good := []project.Scene{
+		{Name: "a", Require: []project.Condition{{Element: "main"}}},
+		{Name: "b", Require: []project.Condition{{Element: "main"}}, Forbid: []project.Condition{{Element: "game"}}},
+	}
+	err := project.VerifyScene(good)
+	if err != nil {
+		t.Error("case [good] verify failed:", err)
+	}
+	bads := map[string][]project.Scene{
+		// Name 为空
+		"bad1": {{Name: "", Require: []project.Condition{{Element: "main"}}}},
+		// Name 重复
+		"bad2": {
+			{Name: "a", Require: []project.Condition{{Element: "main"}}},
+			{Name: "a", Require: []project.Condition{{Element: "main"}}},
+		},
+		// 没有 Require
+		"bad3": {{Name: "a", Forbid: []project.Condition{{Element: "main"}}}},
+		// Condition 的 Element 为空
+		"bad4": {{Name: "a", Require: []project.Condition{{Text: "main"}}}},
+		"bad5": {{Name: "a", Require: []project.Condition{{Element: "main"}}, Forbid: []project.Condition{{}}}},
+	}
+	for k, v := range bads {
+		err = project.VerifyScene(v)
+		if err == nil {
+			t.Error("case [" + k + "] should be an error, but not")
+		}
+	}
+}
+
+func TestVerifySceneElement(t *testing.T) {
+	es := []project.Element{{Name: "main", Element: []project.Element{
+		{Name: "coin", Type: project.ElTypeArea},
+		{Name: "start", Type: project.ElTypeImg},
+	}}}
+	good := []project.Scene{{Name: "a", Require: []project.Condition{{Element: "main.coin", Text: "金币"}, {Element: "main.start"}}}}
+	err := project.VerifySceneElement(good, es)
+	if err != nil {
+		t.Error("case [good] verify failed:", err)
+	}
+	bads := map[string][]project.Scene{
+		"require": {{Name: "a", Require: []project.Condition{{Element: "main.coin"}}}},
+		"forbid":  {{Name: "a", Require: []project.Condition{{Element: "main.start"}}, Forbid: []project.Condition{{Element: "main.coin"}}}},
+	}
+	for k, v := range bads {
+		err = project.VerifySceneElement(v, es)
+		if err == nil {
+			t.Error("case [" + k + "] should be an error, but not")
+		}
+	}
+}
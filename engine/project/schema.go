@@ -0,0 +1,221 @@
+package project
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// 只实现了 element_schame.json 用到的 JSON Schema (draft-07) 关键字：
+// $ref (仅限 #/definitions/*), type, properties, additionalProperties (bool),
+// required, items, minItems, pattern, enum, minimum, maximum
+type schema struct {
+	Ref                  string             `json:"$ref"`
+	Type                 string             `json:"type"`
+	Properties           map[string]*schema `json:"properties"`
+	AdditionalProperties *bool              `json:"additionalProperties"`
+	Required             []string           `json:"required"`
+	Items                *schema            `json:"items"`
+	MinItems             *int               `json:"minItems"`
+	Pattern              string             `json:"pattern"`
+	Enum                 []any              `json:"enum"`
+	Minimum              *float64           `json:"minimum"`
+	Maximum              *float64           `json:"maximum"`
+	Definitions          map[string]*schema `json:"definitions"`
+}
+
+// 一个不符合 Schema 的地方，Line 和 Column 是在 yaml 文件中的位置
+type SchemaError struct {
+	Line   int
+	Column int
+	Path   string
+	Msg    string
+}
+
+func (e SchemaError) Error() string {
+	return fmt.Sprintf("line %d, column %d: [%s] %s", e.Line, e.Column, e.Path, e.Msg)
+}
+
+// 所有不符合 Schema 的地方
+type SchemaErrors []SchemaError
+
+func (es SchemaErrors) Error() string {
+	msgs := make([]string, 0, len(es))
+	for _, e := range es {
+		msgs = append(msgs, e.Error())
+	}
+	return "schema validation failed:\n\t" + strings.Join(msgs, "\n\t")
+}
+
+// 使用 element_schame.json 验证 yaml 格式的 Element 文件
+// 如果有不符合的地方，返回 SchemaErrors
+func ValidateElement(data []byte) error {
+	return validateSchema(elementSchame, data)
+}
+
+func validateSchema(schemaB []byte, data []byte) error {
+	root := new(schema)
+	err := json.Unmarshal(schemaB, root)
+	if err != nil {
+		return fmt.Errorf("invalid schema: %w", err)
+	}
+	doc := new(yaml.Node)
+	err = yaml.Unmarshal(data, doc)
+	if err != nil {
+		return err
+	}
+	// 空文件
+	if len(doc.Content) == 0 {
+		return nil
+	}
+	v := &validator{root: root}
+	v.validate(root, doc.Content[0], "$")
+	if len(v.errs) != 0 {
+		return v.errs
+	}
+	return nil
+}
+
+type validator struct {
+	root *schema
+	errs SchemaErrors
+}
+
+func (v *validator) addErr(n *yaml.Node, path string, format string, a ...any) {
+	v.errs = append(v.errs, SchemaError{
+		Line:   n.Line,
+		Column: n.Column,
+		Path:   path,
+		Msg:    fmt.Sprintf(format, a...),
+	})
+}
+
+func (v *validator) validate(s *schema, n *yaml.Node, path string) {
+	if n.Kind == yaml.AliasNode {
+		n = n.Alias
+	}
+	if s.Ref != "" {
+		name := strings.TrimPrefix(s.Ref, "#/definitions/")
+		ref, ok := v.root.Definitions[name]
+		if !ok {
+			v.addErr(n, path, "undefined $ref [%s] in schema", s.Ref)
+			return
+		}
+		s = ref
+	}
+	if s.Type != "" && !matchType(s.Type, n) {
+		v.addErr(n, path, "want type [%s], got [%s]", s.Type, nodeType(n))
+		return
+	}
+	switch n.Kind {
+	case yaml.MappingNode:
+		v.validateObject(s, n, path)
+	case yaml.SequenceNode:
+		if s.MinItems != nil && len(n.Content) < *s.MinItems {
+			v.addErr(n, path, "want at least %d items, got %d", *s.MinItems, len(n.Content))
+		}
+		if s.Items != nil {
+			for i, item := range n.Content {
+				v.validate(s.Items, item, path+"["+strconv.Itoa(i)+"]")
+			}
+		}
+	case yaml.ScalarNode:
+		v.validateScalar(s, n, path)
+	}
+}
+
+func (v *validator) validateObject(s *schema, n *yaml.Node, path string) {
+	keys := make(map[string]struct{})
+	for i := 0; i+1 < len(n.Content); i += 2 {
+		k, val := n.Content[i], n.Content[i+1]
+		keys[k.Value] = struct{}{}
+		p := path + "." + k.Value
+		sub, ok := s.Properties[k.Value]
+		if !ok {
+			if s.AdditionalProperties != nil && !*s.AdditionalProperties {
+				v.addErr(k, p, "additional property is not allowed")
+			}
+			continue
+		}
+		v.validate(sub, val, p)
+	}
+	required := append([]string{}, s.Required...)
+	sort.Strings(required)
+	for _, r := range required {
+		if _, ok := keys[r]; !ok {
+			v.addErr(n, path, "missing required property [%s]", r)
+		}
+	}
+}
+
+func (v *validator) validateScalar(s *schema, n *yaml.Node, path string) {
+	if s.Pattern != "" && n.Tag == "!!str" {
+		re, err := regexp.Compile(s.Pattern)
+		if err != nil {
+			v.addErr(n, path, "invalid pattern [%s] in schema", s.Pattern)
+		} else if !re.MatchString(n.Value) {
+			v.addErr(n, path, "[%s] does not match the pattern [%s]", n.Value, s.Pattern)
+		}
+	}
+	if len(s.Enum) != 0 {
+		found := false
+		for _, e := range s.Enum {
+			if fmt.Sprint(e) == n.Value {
+				found = true
+				break
+			}
+		}
+		if !found {
+			v.addErr(n, path, "[%s] is not one of %v", n.Value, s.Enum)
+		}
+	}
+	if s.Minimum == nil && s.Maximum == nil {
+		return
+	}
+	f, err := strconv.ParseFloat(n.Value, 64)
+	if err != nil {
+		return
+	}
+	if s.Minimum != nil && f < *s.Minimum {
+		v.addErr(n, path, "[%s] is less than the minimum [%v]", n.Value, *s.Minimum)
+	}
+	if s.Maximum != nil && f > *s.Maximum {
+		v.addErr(n, path, "[%s] is greater than the maximum [%v]", n.Value, *s.Maximum)
+	}
+}
+
+func matchType(t string, n *yaml.Node) bool {
+	got := nodeType(n)
+	if t == "number" && got == "integer" {
+		return true
+	}
+	return t == got
+}
+
+// 将 yaml 节点的类型转换成 JSON Schema 中的类型
+func nodeType(n *yaml.Node) string {
+	switch n.Kind {
+	case yaml.MappingNode:
+		return "object"
+	case yaml.SequenceNode:
+		return "array"
+	}
+	switch n.Tag {
+	case "!!str":
+		return "string"
+	case "!!int":
+		return "integer"
+	case "!!float":
+		return "number"
+	case "!!bool":
+		return "boolean"
+	case "!!null":
+		return "null"
+	}
+	return n.Tag
+}
@@ -0,0 +1,22 @@
+-- 运行时第一个参数是 give-me-time 的地址，格式为 host:port
+-- api 使用 http 调用：POST /<方法名>，body 是 json 数组形式的参数，返回 {"result": ...} 或者 {"error": "..."}
+-- lua 没有内置 http，这里使用 curl 发送请求
+local addr = arg[1]
+
+local function call(method, args)
+    local f = assert(io.popen(string.format("curl -s -X POST -d '%s' http://%s/%s", args or "[]", addr, method)))
+    local out = f:read("*a")
+    f:close()
+    if out:find('"error"') then
+        error(method .. ": " .. out)
+    end
+    return out
+end
+
+print("hello give-me-time, connect to " .. tostring(addr))
+-- option.json 中的选项，返回值是 {"result": 1}
+local times = tonumber(call("GetOption", '["times"]'):match('"result":%s*([%d.]+)')) or 1
+-- 按下 element/main.yaml 中的 main.start
+for i = 1, times do
+    call("PressE", '["main.start", 0]')
+end
@@ -0,0 +1,39 @@
+// 运行时第一个参数是 give-me-time 的地址，格式为 host:port
+const http = require("http");
+
+const addr = process.argv[2] || "";
+
+// 调用 give-me-time 的 api：POST /<方法名>，body 是 json 数组形式的参数
+function call(method, ...args) {
+    return new Promise((resolve, reject) => {
+        const req = http.request("http://" + addr + "/" + method, { method: "POST" }, (res) => {
+            let body = "";
+            res.on("data", (chunk) => (body += chunk));
+            res.on("end", () => {
+                const r = JSON.parse(body);
+                if (r.error) {
+                    reject(new Error(method + ": " + r.error));
+                } else {
+                    resolve(r.result);
+                }
+            });
+        });
+        req.on("error", reject);
+        req.end(JSON.stringify(args));
+    });
+}
+
+async function main() {
+    console.log("hello give-me-time, connect to " + addr);
+    // option.json 中的选项
+    const times = await call("GetOption", "times");
+    // 按下 element/main.yaml 中的 main.start
+    for (let i = 0; i < times; i++) {
+        await call("PressE", "main.start", 0);
+    }
+}
+
+main().catch((err) => {
+    console.error(err);
+    process.exit(1);
+});
@@ -0,0 +1,32 @@
+import json
+import sys
+import urllib.error
+import urllib.request
+
+# 运行时第一个参数是 give-me-time 的地址，格式为 host:port
+addr = sys.argv[1] if len(sys.argv) > 1 else ""
+
+
+def call(method, *args):
+    """调用 give-me-time 的 api：POST /<方法名>，body 是 json 数组形式的参数"""
+    req = urllib.request.Request(
+        "http://%s/%s" % (addr, method),
+        data=json.dumps(list(args)).encode(),
+        headers={"Content-Type": "application/json"},
+    )
+    try:
+        resp = urllib.request.urlopen(req)
+    except urllib.error.HTTPError as e:
+        resp = e
+    r = json.load(resp)
+    if "error" in r:
+        raise RuntimeError("%s: %s" % (method, r["error"]))
+    return r.get("result")
+
+
+print("hello give-me-time, connect to " + addr)
+# option.json 中的选项
+times = call("GetOption", "times")
+# 按下 element/main.yaml 中的 main.start
+for i in range(int(times)):
+    call("PressE", "main.start", 0)
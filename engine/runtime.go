@@ -0,0 +1,95 @@
+package engine
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"runtime"
+	"strconv"
+	"strings"
+
+	"github.com/HumXC/give-me-time/engine/project"
+)
+
+// 传递给运行环境的环境变量，值是 json 格式的选项
+const EnvOptions = "GMT_OPTIONS"
+
+// 根据 Runtime.Run 创建运行脚本的命令，dir 是命令的工作目录
+// Run 中的占位符 [HOST], [PORT] 和 [OPTIONS] 会被替换，[OPTIONS] 是经过 shell 转义的 json 格式的选项，
+// 命令行可以被其他用户看到，[OPTIONS] 中 defs 里 secret 类型的值会被隐藏，完整的选项只通过环境变量 EnvOptions 传递
+func NewRuntimeCmd(rt project.Runtime, dir, host string, port int, defs []project.Option, opts map[string]any) (*exec.Cmd, error) {
+	if opts == nil {
+		opts = map[string]any{}
+	}
+	optB, err := json.Marshal(opts)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal options: %w", err)
+	}
+	maskedB, err := json.Marshal(project.MaskOption(defs, opts))
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal options: %w", err)
+	}
+	run := strings.NewReplacer(
+		"[HOST]", host,
+		"[PORT]", strconv.Itoa(port),
+		"[OPTIONS]", shellQuote(string(maskedB)),
+	).Replace(rt.Run)
+	var cmd *exec.Cmd
+	if runtime.GOOS == "windows" {
+		cmd = exec.Command("cmd", "/C", run)
+	} else {
+		cmd = exec.Command("sh", "-c", run)
+	}
+	cmd.Dir = dir
+	cmd.Env = append(os.Environ(), EnvOptions+"="+string(optB))
+	return cmd, nil
+}
+
+// 启动提供 c 的 Server，然后在 dir 中运行 c.Info.Runtime，直到脚本退出或者 ctx 结束
+// 脚本的输出会写入 c.LogFile，选项使用 c.Option 的值，c.OptionDefs 中 secret 类型的选项不会出现在命令行中
+func RunRuntime(ctx context.Context, c *Client, dir string) error {
+	makeErr := func(err error) error {
+		return fmt.Errorf("failed to run runtime [%s]: %w", c.Info.Runtime.Name, err)
+	}
+	s, err := NewServer("127.0.0.1:0", c.Methods())
+	if err != nil {
+		return makeErr(err)
+	}
+	defer s.Close()
+	go func() { _ = s.Serve() }()
+	host, port := s.Addr()
+	cmd, err := NewRuntimeCmd(c.Info.Runtime, dir, host, port, c.OptionDefs, c.Option.GetOptions())
+	if err != nil {
+		return makeErr(err)
+	}
+	if c.LogFile != nil {
+		cmd.Stdout = c.LogFile
+		cmd.Stderr = c.LogFile
+	}
+	err = cmd.Start()
+	if err != nil {
+		return makeErr(err)
+	}
+	done := make(chan error, 1)
+	go func() { done <- cmd.Wait() }()
+	select {
+	case err = <-done:
+	case <-ctx.Done():
+		_ = cmd.Process.Kill()
+		<-done
+		err = ctx.Err()
+	}
+	if err != nil {
+		return makeErr(err)
+	}
+	return nil
+}
+
+func shellQuote(s string) string {
+	if runtime.GOOS == "windows" {
+		return `"` + strings.ReplaceAll(s, `"`, `\"`) + `"`
+	}
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
@@ -0,0 +1,83 @@
+package engine_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/HumXC/give-me-time/engine"
+	"github.com/HumXC/give-me-time/engine/project"
+)
+
+func TestNewRuntimeCmd(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("sh is required")
+	}
+	opts := map[string]any{"name": "it's me", "times": 3}
+	rt := project.Runtime{Run: "echo [HOST]:[PORT] [OPTIONS]; echo $" + engine.EnvOptions}
+	cmd, err := engine.NewRuntimeCmd(rt, t.TempDir(), "127.0.0.1", 8080, nil, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	out, err := cmd.Output()
+	if err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSpace(string(out)), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("unexpected output: %s", out)
+	}
+	addr, arg, _ := strings.Cut(lines[0], " ")
+	if addr != "127.0.0.1:8080" {
+		t.Errorf("want: 127.0.0.1:8080, got: %s", addr)
+	}
+	for _, s := range []string{arg, lines[1]} {
+		m := make(map[string]any)
+		err := json.Unmarshal([]byte(s), &m)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if m["name"] != "it's me" || m["times"] != 3.0 {
+			t.Errorf("unexpected options: %v", m)
+		}
+	}
+}
+
+func TestNewRuntimeCmdSecret(t *testing.T) {
+	defs := []project.Option{{Name: "user", Type: project.OptionString}, {Name: "password", Type: project.OptionSecret}}
+	opts := map[string]any{"user": "jack", "password": "123456"}
+	rt := project.Runtime{Run: "echo [OPTIONS]"}
+	cmd, err := engine.NewRuntimeCmd(rt, t.TempDir(), "127.0.0.1", 8080, defs, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 命令行中没有 secret 的值，环境变量中有
+	for _, arg := range cmd.Args {
+		if strings.Contains(arg, "123456") {
+			t.Errorf("secret in args: %v", cmd.Args)
+		}
+	}
+	if !strings.Contains(strings.Join(cmd.Args, " "), "jack") {
+		t.Errorf("option [user] should be in args: %v", cmd.Args)
+	}
+	env := ""
+	for _, e := range cmd.Env {
+		if strings.HasPrefix(e, engine.EnvOptions+"=") {
+			env = e
+		}
+	}
+	if !strings.Contains(env, "123456") {
+		t.Errorf("secret should be in %s, got: %s", engine.EnvOptions, env)
+	}
+}
+
+func TestLogPrintOption(t *testing.T) {
+	opts := []project.Option{{Name: "user", Type: project.OptionString}, {Name: "password", Type: project.OptionSecret}}
+	buf := new(bytes.Buffer)
+	engine.LogPrintOption(buf, opts, map[string]any{"user": "jack", "password": "123456"})
+	if strings.Contains(buf.String(), "123456") || !strings.Contains(buf.String(), "jack") {
+		t.Errorf("unexpected log: %s", buf.String())
+	}
+}
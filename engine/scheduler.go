@@ -0,0 +1,95 @@
+package engine
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sync"
+	"time"
+
+	"github.com/HumXC/give-me-time/devices"
+	"golang.org/x/exp/slog"
+)
+
+// 在一个设备上执行的任务，每个设备都有自己的 Client 和 log
+// Client 中只设置了 Device 和 LogFile，任务需要自己创建 ApiImg 等资源，不能与其他设备共享
+type DeviceJob func(ctx context.Context, c *Client, log *slog.Logger) error
+
+// 一个设备的执行结果
+type DeviceResult struct {
+	Device   devices.Device
+	LogFile  string
+	Err      error
+	Duration time.Duration
+}
+
+// 设备 ID 中不能作为文件名的字符，例如无线设备的 "192.168.1.2:5555"
+var unsafeFileChar = regexp.MustCompile(`[^A-Za-z0-9._-]`)
+
+// 为每个设备并发地执行 job，每个设备的日志写入 logDir 中的 <设备 ID>.log
+// 一个设备失败或者 panic 不会影响其他设备，返回的结果与 ds 的顺序相同
+func RunDevices(ctx context.Context, ds []devices.Device, logDir string, job DeviceJob) []DeviceResult {
+	results := make([]DeviceResult, len(ds))
+	wg := sync.WaitGroup{}
+	for i, d := range ds {
+		wg.Add(1)
+		go func(i int, d devices.Device) {
+			defer wg.Done()
+			start := time.Now()
+			logFile, err := runDevice(ctx, d, logDir, job)
+			results[i] = DeviceResult{
+				Device:   d,
+				LogFile:  logFile,
+				Err:      err,
+				Duration: time.Since(start),
+			}
+		}(i, d)
+	}
+	wg.Wait()
+	return results
+}
+
+func runDevice(ctx context.Context, d devices.Device, logDir string, job DeviceJob) (logFile string, err error) {
+	err = os.MkdirAll(logDir, 0755)
+	if err != nil {
+		return "", err
+	}
+	logFile = filepath.Join(logDir, unsafeFileChar.ReplaceAllString(d.ID(), "_")+".log")
+	f, err := os.Create(logFile)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	log := slog.New(LogHandler(f))
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("panic: %v", r)
+			log.Error("device panic", "err", err)
+		}
+	}()
+	err = job(ctx, &Client{Device: d, LogFile: f}, log)
+	if err != nil {
+		log.Error("device failed", "err", err)
+	}
+	return logFile, err
+}
+
+// 输出所有设备的执行结果
+func PrintSummary(w io.Writer, results []DeviceResult) {
+	failed := 0
+	msg := "Summary:\n"
+	for _, r := range results {
+		status := "ok"
+		if r.Err != nil {
+			status = "failed: " + r.Err.Error()
+			failed++
+		}
+		msg += fmt.Sprintf("	%s %s, %s, log: %s\n",
+			r.Device.ID(), r.Duration.Round(time.Millisecond), status, r.LogFile)
+	}
+	msg += fmt.Sprintf("%d devices, %d ok, %d failed\n", len(results), len(results)-failed, failed)
+	_, _ = io.WriteString(w, msg)
+}
@@ -0,0 +1,52 @@
+package engine_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/HumXC/adb-helper"
+	"github.com/HumXC/give-me-time/devices"
+	"github.com/HumXC/give-me-time/engine"
+	"golang.org/x/exp/slog"
+)
+
+func TestRunDevices(t *testing.T) {
+	ds := []devices.Device{
+		devices.NewADBDevice(adb.Device{ID: "a"}),
+		devices.NewADBDevice(adb.Device{ID: "192.168.1.2:5555"}),
+		devices.NewADBDevice(adb.Device{ID: "c"}),
+	}
+	job := func(ctx context.Context, c *engine.Client, log *slog.Logger) error {
+		log.Info("hello", "device", c.Device.ID())
+		switch c.Device.ID() {
+		case "a":
+			return errors.New("boom")
+		case "c":
+			panic("oops")
+		}
+		return nil
+	}
+	results := engine.RunDevices(context.Background(), ds, t.TempDir(), job)
+	if len(results) != 3 {
+		t.Fatalf("want: 3 results, got: %d", len(results))
+	}
+	if results[0].Err == nil || results[1].Err != nil || results[2].Err == nil {
+		t.Errorf("unexpected results: %+v", results)
+	}
+	b, err := os.ReadFile(results[1].LogFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(results[1].LogFile, ":") || !strings.Contains(string(b), "192.168.1.2:5555") {
+		t.Errorf("unexpected log file [%s]: %s", results[1].LogFile, b)
+	}
+	buf := new(bytes.Buffer)
+	engine.PrintSummary(buf, results)
+	if !strings.Contains(buf.String(), "3 devices, 1 ok, 2 failed") {
+		t.Errorf("unexpected summary: %s", buf.String())
+	}
+}
@@ -0,0 +1,152 @@
+package engine
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"reflect"
+	"strings"
+	"sync"
+)
+
+// 把 api 提供给运行环境中的脚本，脚本通过 Runtime.Run 中的 [HOST] 和 [PORT] 连接
+// 调用方法时使用 POST /<方法名>，body 是 json 数组形式的参数，例如：
+//
+//	POST /PressE
+//	["main.start", 0]
+//
+// 返回 {"result": ...} 或者 {"error": "..."}，方法有多个返回值时 result 是数组
+// 同一时间只会执行一个方法
+type Server struct {
+	methods  map[string]reflect.Value
+	mu       sync.Mutex
+	listener net.Listener
+	srv      *http.Server
+}
+
+type serverResponse struct {
+	Result any    `json:"result,omitempty"`
+	Error  string `json:"error,omitempty"`
+}
+
+var errorType = reflect.TypeOf((*error)(nil)).Elem()
+
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	reply := func(code int, resp serverResponse) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(code)
+		_ = json.NewEncoder(w).Encode(resp)
+	}
+	if r.Method != http.MethodPost {
+		reply(http.StatusMethodNotAllowed, serverResponse{Error: "method must be POST"})
+		return
+	}
+	name := strings.TrimPrefix(r.URL.Path, "/")
+	fn, ok := s.methods[name]
+	if !ok {
+		reply(http.StatusNotFound, serverResponse{Error: fmt.Sprintf("method [%s] undefiend", name)})
+		return
+	}
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		reply(http.StatusBadRequest, serverResponse{Error: err.Error()})
+		return
+	}
+	args, err := parseArgs(fn.Type(), body)
+	if err != nil {
+		reply(http.StatusBadRequest, serverResponse{Error: fmt.Sprintf("method [%s]: %s", name, err)})
+		return
+	}
+	s.mu.Lock()
+	out := fn.Call(args)
+	s.mu.Unlock()
+	if n := len(out); n != 0 && fn.Type().Out(n-1) == errorType {
+		if e := out[n-1]; !e.IsNil() {
+			reply(http.StatusOK, serverResponse{Error: e.Interface().(error).Error()})
+			return
+		}
+		out = out[:n-1]
+	}
+	resp := serverResponse{}
+	switch len(out) {
+	case 0:
+	case 1:
+		resp.Result = out[0].Interface()
+	default:
+		rs := make([]any, len(out))
+		for i, o := range out {
+			rs[i] = o.Interface()
+		}
+		resp.Result = rs
+	}
+	reply(http.StatusOK, resp)
+}
+
+// 把 body 解析为 t 的参数，body 为空时没有参数
+func parseArgs(t reflect.Type, body []byte) ([]reflect.Value, error) {
+	raws := []json.RawMessage{}
+	if len(strings.TrimSpace(string(body))) != 0 {
+		err := json.Unmarshal(body, &raws)
+		if err != nil {
+			return nil, fmt.Errorf("args must be a json array: %w", err)
+		}
+	}
+	if len(raws) != t.NumIn() {
+		return nil, fmt.Errorf("want %d args, but got %d", t.NumIn(), len(raws))
+	}
+	args := make([]reflect.Value, len(raws))
+	for i, raw := range raws {
+		v := reflect.New(t.In(i))
+		err := json.Unmarshal(raw, v.Interface())
+		if err != nil {
+			return nil, fmt.Errorf("args[%d]: %w", i, err)
+		}
+		args[i] = v.Elem()
+	}
+	return args, nil
+}
+
+// 监听的地址
+func (s *Server) Addr() (host string, port int) {
+	addr := s.listener.Addr().(*net.TCPAddr)
+	return addr.IP.String(), addr.Port
+}
+
+// 处理请求直到 Close 被调用
+func (s *Server) Serve() error {
+	err := s.srv.Serve(s.listener)
+	if errors.Is(err, http.ErrServerClosed) {
+		return nil
+	}
+	return err
+}
+
+func (s *Server) Close() error {
+	return s.srv.Close()
+}
+
+// 在 addr 上监听，例如 "127.0.0.1:0"，methods 的值必须是函数
+// 函数的参数和返回值必须可以转换为 json，最后一个返回值可以是 error
+func NewServer(addr string, methods map[string]any) (*Server, error) {
+	makeErr := func(err error) error {
+		return fmt.Errorf("failed to create server: %w", err)
+	}
+	s := &Server{methods: make(map[string]reflect.Value, len(methods))}
+	for name, m := range methods {
+		fn := reflect.ValueOf(m)
+		if fn.Kind() != reflect.Func || fn.IsNil() {
+			return nil, makeErr(fmt.Errorf("method [%s] is not a func", name))
+		}
+		s.methods[name] = fn
+	}
+	l, err := net.Listen("tcp", addr)
+	if err != nil {
+		return nil, makeErr(err)
+	}
+	s.listener = l
+	s.srv = &http.Server{Handler: s}
+	return s, nil
+}
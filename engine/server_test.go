@@ -0,0 +1,150 @@
+package engine_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"image"
+	"net/http"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/HumXC/give-me-time/engine"
+	"github.com/HumXC/give-me-time/engine/api"
+	"github.com/HumXC/give-me-time/engine/project"
+)
+
+func call(t *testing.T, s *engine.Server, method, args string) (int, map[string]any) {
+	host, port := s.Addr()
+	resp, err := http.Post(fmt.Sprintf("http://%s:%d/%s", host, port, method), "application/json", strings.NewReader(args))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	m := make(map[string]any)
+	err = json.NewDecoder(resp.Body).Decode(&m)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return resp.StatusCode, m
+}
+
+func TestServer(t *testing.T) {
+	s, err := engine.NewServer("127.0.0.1:0", map[string]any{
+		"Add":  func(a, b int) int { return a + b },
+		"Pair": func() (string, int) { return "a", 1 },
+		"Fail": func() error { return errors.New("boom") },
+		"Nop":  func() {},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+	go func() { _ = s.Serve() }()
+	cases := []struct {
+		method, args string
+		code         int
+		want         string
+	}{
+		{"Add", "[1, 2]", http.StatusOK, `map[result:3]`},
+		{"Pair", "", http.StatusOK, `map[result:[a 1]]`},
+		{"Fail", "[]", http.StatusOK, `map[error:boom]`},
+		{"Nop", "[]", http.StatusOK, `map[]`},
+		{"Add", "[1]", http.StatusBadRequest, `map[error:method [Add]: want 2 args, but got 1]`},
+		{"Add", `[1, "2"]`, http.StatusBadRequest, ``},
+		{"Missing", "[]", http.StatusNotFound, `map[error:method [Missing] undefiend]`},
+	}
+	for _, c := range cases {
+		code, m := call(t, s, c.method, c.args)
+		if code != c.code {
+			t.Errorf("case [%s %s] want code: %d, got: %d", c.method, c.args, c.code, code)
+		}
+		if c.want != "" && fmt.Sprint(m) != c.want {
+			t.Errorf("case [%s %s] want: %s, got: %v", c.method, c.args, c.want, m)
+		}
+	}
+	_, err = engine.NewServer("127.0.0.1:0", map[string]any{"a": 1})
+	if err == nil {
+		t.Errorf("case [not a func] should be an error, but not")
+	}
+}
+
+func TestClientMethods(t *testing.T) {
+	adb := &fakeAdb{}
+	c := &engine.Client{
+		Adb:    adb,
+		Img:    &fakeImg{scenes: []string{"home"}, points: map[string]image.Point{"main.start": image.Pt(540, 1800)}},
+		Option: api.NewApiOption(map[string]any{"times": 3}),
+	}
+	s, err := engine.NewServer("127.0.0.1:0", c.Methods())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+	go func() { _ = s.Serve() }()
+	_, m := call(t, s, "PressE", `["main.start", 0]`)
+	if len(m) != 0 || len(adb.presses) != 1 || adb.presses[0] != image.Pt(540, 1800) {
+		t.Errorf("unexpected PressE: %v, %v", m, adb.presses)
+	}
+	_, m = call(t, s, "PressE", `["missing", 0]`)
+	if m["error"] != api.ErrNotFound.Error() {
+		t.Errorf("want: %s, got: %v", api.ErrNotFound, m)
+	}
+	_, m = call(t, s, "GetOption", `["times"]`)
+	if m["result"] != 3.0 {
+		t.Errorf("want: 3, got: %v", m)
+	}
+	code, _ := call(t, s, "Shell", `["reboot"]`)
+	if code != http.StatusNotFound {
+		t.Errorf("Shell should not be provided to scripts")
+	}
+}
+
+func TestRunRuntime(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("sh is required")
+	}
+	dir := t.TempDir()
+	f, err := os.Create(filepath.Join(dir, "test.log"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	c := &engine.Client{
+		Info:    &project.Info{Runtime: project.Runtime{Name: "sh", Run: "echo [HOST] $" + engine.EnvOptions}},
+		LogFile: f,
+		Adb:     &fakeAdb{},
+		Img:     &fakeImg{scenes: []string{"home"}},
+		Option:  api.NewApiOption(map[string]any{"times": 3}),
+	}
+	err = engine.RunRuntime(context.Background(), c, dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := os.ReadFile(f.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.TrimSpace(string(b)) != `127.0.0.1 {"times":3}` {
+		t.Errorf("unexpected output: %s", b)
+	}
+
+	c.Info.Runtime.Run = "sleep 10"
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	err = engine.RunRuntime(ctx, c, dir)
+	if !errors.Is(err, context.DeadlineExceeded) || time.Since(start) > 5*time.Second {
+		t.Errorf("want: %v, got: %v", context.DeadlineExceeded, err)
+	}
+	c.Info.Runtime.Run = "exit 3"
+	err = engine.RunRuntime(context.Background(), c, dir)
+	if err == nil {
+		t.Errorf("case [exit 3] should be an error, but not")
+	}
+}
@@ -11,6 +11,7 @@ require (
 	github.com/HumXC/adb-helper v0.0.0-20230406022903-1b432de6107e
 	github.com/sunshineplan/imgconv v1.1.4
 	golang.org/x/exp v0.0.0-20230321023759-10a507213a29
+	golang.org/x/term v0.6.0
 )
 
 require (
@@ -24,6 +25,7 @@ require (
 	github.com/sunshineplan/pdf v1.0.3 // indirect
 	github.com/sunshineplan/tiff v0.0.0-20220128141034-29b9d69bd906 // indirect
 	golang.org/x/image v0.7.0 // indirect
+	golang.org/x/sys v0.6.0 // indirect
 	golang.org/x/text v0.9.0 // indirect
 	gopkg.in/yaml.v2 v2.4.0 // indirect
 	gopkg.in/yaml.v3 v3.0.1
@@ -1,47 +1,252 @@
 package main
 
 import (
+	"context"
 	"flag"
 	"fmt"
 	"os"
+	"os/signal"
+	"path/filepath"
+	"strings"
 
-	"github.com/HumXC/adb-helper"
 	"github.com/HumXC/give-me-time/devices"
+	"github.com/HumXC/give-me-time/engine"
+	"github.com/HumXC/give-me-time/engine/lint"
+	"github.com/HumXC/give-me-time/engine/project"
+	"github.com/HumXC/give-me-time/tools"
 )
 
 var (
 	deviceID    string
 	projectName string
 	adbPath     string
+	profile     string
+	logDir      string
+	fakePath    string
+	x11Spec     string
 )
 
 func init() {
-	flag.StringVar(&deviceID, "device", "", "指定一个设备，如果为空，则使用第一个设备（如果有）")
+	flag.StringVar(&deviceID, "device", "", "指定设备，多个设备用逗号分隔，为 all 时使用所有在线的设备，如果为空，则使用第一个设备（如果有）")
 	flag.StringVar(&projectName, "run", "", "指定一个工程")
 	flag.StringVar(&adbPath, "adb", "adb", "指定 adb 的路径，默认值为 “adb”")
+	flag.StringVar(&logDir, "log", "log", "日志目录，每个设备的日志是其中的 <设备 ID>.log")
+	flag.StringVar(&fakePath, "fake", "", "使用模拟设备代替 adb 设备，可以是模拟设备的配置文件或者截图所在的目录")
+	flag.StringVar(&x11Spec, "x11", "", "使用 X11 桌面窗口代替 adb 设备，例如 “display=:99,window=My Game,geometry=1280x720+0+40”，为 “root” 时使用整个屏幕")
+	flag.StringVar(&profile, "profile", project.DefaultProfile, "指定用户选项文件，也就是工程的 profiles 目录中的文件名（不含扩展名）")
 	flag.Parse()
 }
 func main() {
+	if flag.NArg() != 0 {
+		err := runCommand(flag.Arg(0), flag.Args()[1:])
+		if err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+		os.Exit(0)
+	}
 	if projectName == "" {
 		os.Exit(0)
 	}
+	p, err := openProject(projectName)
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+	opts, err := loadProfile(projectName, profile, p.Option)
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
 	_adb := devices.NewADB(adbPath)
-	var device *adb.Device
-	if deviceID != "" {
-		d, err := _adb.GetDevice(deviceID)
-		if err != nil {
-			fmt.Println(err)
+	ds, err := selectDevices(&_adb)
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+	dir, cleanup, err := projectDir(projectName)
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+	results := engine.RunDevices(ctx, ds, logDir, projectJob(p, dir, opts))
+	cleanup()
+	engine.PrintSummary(os.Stdout, results)
+	for _, r := range results {
+		if r.Err != nil {
+			stop()
 			os.Exit(1)
 		}
-		device = d
-	} else {
-		d, err := _adb.FirstDevice()
+	}
+}
+
+// 读取工程 name 的 profile，工程是打包后的文件时，profiles 目录与工程文件在同一个目录
+func loadProfile(name, profile string, opts []project.Option) (map[string]any, error) {
+	dir := name
+	if filepath.Ext(name) == project.ArchiveExt {
+		dir = filepath.Dir(name)
+	}
+	file, err := project.ProfilePath(dir, profile)
+	if err != nil {
+		return nil, err
+	}
+	return project.ParseOption(opts, file)
+}
+
+// 打开一个工程，name 可以是工程目录或者打包后的工程文件
+func openProject(name string) (*project.Project, error) {
+	if filepath.Ext(name) != project.ArchiveExt {
+		return project.Open(os.DirFS(name))
+	}
+	fsys, _, err := project.OpenArchiveFile(name)
+	if err != nil {
+		return nil, err
+	}
+	return project.Open(fsys)
+}
+
+// 返回运行工程脚本的目录，打包后的工程会被解压到临时目录中，cleanup 会删除这个目录
+func projectDir(name string) (dir string, cleanup func(), err error) {
+	if filepath.Ext(name) != project.ArchiveExt {
+		return name, func() {}, nil
+	}
+	dir, err = os.MkdirTemp("", "gmt-")
+	if err != nil {
+		return "", nil, err
+	}
+	cleanup = func() { _ = os.RemoveAll(dir) }
+	err = project.Unpack(name, dir)
+	if err != nil {
+		cleanup()
+		return "", nil, err
+	}
+	return dir, cleanup, nil
+}
+
+// 执行子命令
+//   - init [-runtime lua] [-name name] <dir>：在 dir 中创建一个新的工程
+//   - config [-profile name] <project>：交互式地编辑工程的用户选项
+//   - devices [-json]：列出所有设备的信息，参考 runDevices
+//   - lint <project>：检查工程中的问题，project 可以是工程目录或者打包后的工程文件
+//   - pack <dir> [file]：将 dir 中的工程打包成 file，file 默认为 <dir>.gmt
+//   - unpack <file> [dir]：校验并解压 file 中的工程到 dir，dir 默认为去掉扩展名的 file
+func runCommand(cmd string, args []string) error {
+	switch cmd {
+	case "init":
+		fs := flag.NewFlagSet("init", flag.ContinueOnError)
+		rt := fs.String("runtime", "lua", "工程的运行环境，可以是 lua, go, python, node")
+		name := fs.String("name", "", "工程的名称，默认为目录名")
+		err := fs.Parse(args)
 		if err != nil {
-			fmt.Println(err)
-			os.Exit(1)
+			return err
 		}
-		device = d
+		if fs.NArg() == 0 {
+			return fmt.Errorf("usage: init [-runtime lua] [-name name] <dir>")
+		}
+		dir := fs.Arg(0)
+		if *name == "" {
+			abs, err := filepath.Abs(dir)
+			if err != nil {
+				return err
+			}
+			*name = filepath.Base(abs)
+		}
+		return project.Init(dir, *name, *rt)
+	case "config":
+		return runConfig(args)
+	case "devices":
+		return runDevices(args)
+	case "lint":
+		if len(args) == 0 {
+			return fmt.Errorf("usage: lint <project>")
+		}
+		p, err := openProject(args[0])
+		if err != nil {
+			return err
+		}
+		ps := lint.Lint(p)
+		for _, p := range ps {
+			fmt.Println(p)
+		}
+		if len(ps) != 0 {
+			return fmt.Errorf("found %d problems", len(ps))
+		}
+		return nil
+	case "pack":
+		if len(args) == 0 {
+			return fmt.Errorf("usage: pack <dir> [file]")
+		}
+		dir := args[0]
+		out := filepath.Clean(dir) + project.ArchiveExt
+		if len(args) > 1 {
+			out = args[1]
+		}
+		f, err := os.Create(out)
+		if err != nil {
+			return err
+		}
+		err = project.Pack(os.DirFS(dir), f)
+		if cerr := f.Close(); err == nil {
+			err = cerr
+		}
+		if err != nil {
+			os.Remove(out)
+		}
+		return err
+	case "unpack":
+		if len(args) == 0 {
+			return fmt.Errorf("usage: unpack <file> [dir]")
+		}
+		file := args[0]
+		dir := strings.TrimSuffix(file, filepath.Ext(file))
+		if len(args) > 1 {
+			dir = args[1]
+		}
+		return project.Unpack(file, dir)
+	}
+	return fmt.Errorf("unknown command [%s]", cmd)
+}
+
+// 设置了 -fake 或者 -x11 时只使用模拟设备或者 X11 设备，否则根据 -device 选择 adb 设备
+func selectDevices(_adb *devices.ADB) ([]devices.Device, error) {
+	if fakePath != "" {
+		f, err := devices.LoadFake(os.DirFS(filepath.Dir(fakePath)), filepath.Base(fakePath))
+		if err != nil {
+			return nil, err
+		}
+		return []devices.Device{f}, nil
+	}
+	if x11Spec != "" {
+		spec := x11Spec
+		if spec == "root" {
+			spec = ""
+		}
+		c, err := devices.ParseX11(spec)
+		if err != nil {
+			return nil, err
+		}
+		d, err := devices.NewX11Device(c)
+		if err != nil {
+			return nil, err
+		}
+		return []devices.Device{d}, nil
+	}
+	// 只连接 -device 明确指定或者 all 选择的已知无线设备，-device 为空时不会连接
+	for addr, err := range _adb.ConnectKnown(deviceID) {
+		fmt.Printf("%s: %v\n", addr, err)
+	}
+	selected, err := _adb.SelectDevices(deviceID)
+	if err != nil {
+		return nil, err
+	}
+	ds := make([]devices.Device, 0, len(selected))
+	for _, d := range selected {
+		// 截图使用推送到设备上的 screencap
+		device := devices.NewADBDevice(d)
+		device.Setup = tools.InitTools
+		ds = append(ds, device)
 	}
-	// 占个位先
-	_ = fmt.Sprint(device)
+	return ds, nil
 }
@@ -0,0 +1,62 @@
+package main
+
+import (
+	"context"
+
+	"github.com/HumXC/give-me-time/devices"
+	"github.com/HumXC/give-me-time/engine"
+	"github.com/HumXC/give-me-time/engine/api"
+	"github.com/HumXC/give-me-time/engine/project"
+	"golang.org/x/exp/slog"
+)
+
+// 返回在一个设备上执行工程中所有 Flow，然后在 dir 中运行工程脚本的任务
+// 每个设备都会创建自己的 ApiImg，也就有自己的截图工具和 OCR 客户端
+// 实现了 devices.Reconnector 的设备断开连接时会重连
+func projectJob(p *project.Project, dir string, opts map[string]any) engine.DeviceJob {
+	return func(ctx context.Context, c *engine.Client, log *slog.Logger) error {
+		device := c.Device
+		log.Info("start", "project", p.Info.Name, "device", device.ID())
+		engine.LogPrintHead(c.LogFile, dir, device)
+		engine.LogPrintOption(c.LogFile, p.Option, opts)
+		err := devices.Init(device)
+		if err != nil {
+			return err
+		}
+		reconnector, canReconnect := device.(devices.Reconnector)
+		// 元素使用游戏的逻辑坐标，截图和输入会根据设备的旋转转换
+		device = devices.NewOrientedDevice(device, p.Info.Orientation)
+		size, err := device.ScreenSize()
+		if err != nil {
+			return err
+		}
+		elImg, elArea, elPoint, elColor, err := p.ParseElement(size)
+		if err != nil {
+			return err
+		}
+		img, err := api.NewApiImg(device, elImg, elArea, elPoint, elColor, p.Scene)
+		if err != nil {
+			return err
+		}
+		input := api.NewApiAdb(device)
+		for _, f := range p.Flow {
+			r, err := engine.NewFlowRunner(f, input, img, elImg, elArea, elPoint, elColor, p.Scene, log)
+			if err != nil {
+				return err
+			}
+			if canReconnect {
+				r.OnDisconnect(reconnector.Reconnect)
+			}
+			err = r.Run(ctx)
+			if err != nil {
+				return err
+			}
+		}
+		c.Info = &p.Info
+		c.Adb = input
+		c.Img = img
+		c.Option = api.NewApiOption(opts)
+		c.OptionDefs = p.Option
+		return engine.RunRuntime(ctx, c, dir)
+	}
+}
@@ -7,15 +7,15 @@ import (
 	"github.com/HumXC/give-me-time/tools"
 )
 
-func TestXxx(t *testing.T) {
+// 需要连接 adb 设备，没有设备时跳过
+func TestInitTools(t *testing.T) {
 	server := adb.DefaultServer()
 	ds, err := server.Devices()
-	if err != nil {
-		t.Fatal(err)
+	if err != nil || len(ds) == 0 {
+		t.Skip("no adb device:", err)
 	}
 	err = tools.InitTools(ds[0])
 	if err != nil {
-		panic(err)
+		t.Fatal(err)
 	}
-
 }